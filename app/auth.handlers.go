@@ -10,6 +10,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -19,7 +20,11 @@ const userContextKey = contextKey("user")
 
 const cookieSessionName = "session"
 
-var sessions = map[string]session{}
+// Sessions are shared by all requests, sessionsMu guards every access
+var (
+	sessions   = map[string]session{}
+	sessionsMu sync.RWMutex
+)
 
 type session struct {
 	username string
@@ -69,14 +74,25 @@ func SignInHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user.SuspendedAt != nil {
+		http.Error(w, "Account is suspended", http.StatusForbidden)
+		return
+	}
+
+	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"lastSignInAt": time.Now()}})
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
 	sessionToken, _ := generateSessionToken()
 	expiresAt := time.Now().Add(60 * 60 * 10 * time.Second)
 
-	sessions[sessionToken] = session{
-		username: credentials.Username,
+	addSession(sessionToken, session{
+		username: user.Name,
 		userId:   user.ID,
 		expiry:   expiresAt,
-	}
+	})
 
 	http.SetCookie(w, &http.Cookie{
 		Name:    cookieSessionName,
@@ -102,7 +118,7 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 
 	sessionToken := cookie.Value
 
-	delete(sessions, sessionToken)
+	deleteSession(sessionToken)
 
 	http.SetCookie(w, &http.Cookie{
 		Name:    cookieSessionName,
@@ -124,10 +140,10 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		}
 
 		sessionToken := cookie.Value
-		userSession, exists := sessions[sessionToken]
+		userSession, exists := findSession(sessionToken)
 		if !exists || userSession.isExpired() {
 			if exists {
-				delete(sessions, sessionToken)
+				deleteSession(sessionToken)
 			}
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
@@ -143,9 +159,51 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+func addSession(token string, userSession session) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	sessions[token] = userSession
+}
+
+func findSession(token string) (session, bool) {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	userSession, exists := sessions[token]
+	return userSession, exists
+}
+
+func deleteSession(token string) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	delete(sessions, token)
+}
+
+// deleteUserSessions signs the user out everywhere.
+func deleteUserSessions(userID primitive.ObjectID) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	for token, userSession := range sessions {
+		if userSession.userId == userID {
+			delete(sessions, token)
+		}
+	}
+}
+
+// renameSessions updates the name kept in sessions of the user.
+func renameSessions(userID primitive.ObjectID, name string) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	for token, userSession := range sessions {
+		if userSession.userId == userID {
+			userSession.username = name
+			sessions[token] = userSession
+		}
+	}
+}
+
 func getUserByName(collection *mongo.Collection, name string) (*User, error) {
 	var user User
-	filter := bson.M{"name": name}
+	filter := bson.M{"nameKey": usernameKey(name)}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
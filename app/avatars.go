@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// avatarSizes are edges of the square images rendered for every avatar, in pixels.
+var avatarSizes = []int{48, 128, 512}
+
+// Avatar is the current avatar image of a user. Every upload gets a new ID, so its files never change
+// and can be cached forever. It's rendered as links to every size keyed by the size.
+type Avatar struct {
+	ID primitive.ObjectID `bson:"_id"`
+}
+
+func (a *Avatar) MarshalJSON() ([]byte, error) {
+	urls := make(map[string]string, len(avatarSizes))
+	for _, size := range avatarSizes {
+		urls[strconv.Itoa(size)] = "/" + avatarKey(a.ID, size)
+	}
+	return json.Marshal(urls)
+}
+
+// avatarKey is the blob key of the avatar image of the size. Avatar URLs are the same paths.
+func avatarKey(id primitive.ObjectID, size int) string {
+	return fmt.Sprintf("avatars/%s/%d.jpg", id.Hex(), size)
+}
+
+// renderAvatar crops the uploaded image to a centered square and renders it in every avatar size as JPEG.
+// Transparent areas become white.
+func renderAvatar(data []byte) (map[int][]byte, error) {
+	switch http.DetectContentType(data) {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, errUnsupportedMedia
+	}
+
+	imageConfig, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errUnsupportedMedia
+	}
+	if imageConfig.Width*imageConfig.Height > maxImagePixels {
+		return nil, errMediaTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errUnsupportedMedia
+	}
+
+	square := cropSquare(img)
+
+	images := make(map[int][]byte, len(avatarSizes))
+	for _, size := range avatarSizes {
+		resized := resizeImage(square, size, size)
+
+		canvas := image.NewRGBA(resized.Bounds())
+		draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(canvas, canvas.Bounds(), resized, image.Point{}, draw.Over)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, canvas, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		images[size] = buf.Bytes()
+	}
+
+	return images, nil
+}
+
+// cropSquare returns the largest centered square of the image.
+func cropSquare(img image.Image) image.Image {
+	bounds := img.Bounds()
+	edge := min(bounds.Dx(), bounds.Dy())
+	x0 := bounds.Min.X + (bounds.Dx()-edge)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-edge)/2
+	square := image.Rect(x0, y0, x0+edge, y0+edge)
+
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(square)
+	}
+
+	cropped := image.NewRGBA(image.Rect(0, 0, edge, edge))
+	draw.Draw(cropped, cropped.Bounds(), img, square.Min, draw.Src)
+	return cropped
+}
+
+// setAvatar stores images of the new avatar of the user and removes files of the replaced one.
+// With nil images the avatar is removed.
+func setAvatar(ctx context.Context, userID primitive.ObjectID, images map[int][]byte) (*Avatar, error) {
+	var avatar *Avatar
+	update := bson.M{"$unset": bson.M{"avatar": ""}}
+
+	if images != nil {
+		avatar = &Avatar{ID: primitive.NewObjectID()}
+		for size, data := range images {
+			if err := blobStore.Put(ctx, avatarKey(avatar.ID, size), data, "image/jpeg"); err != nil {
+				return nil, err
+			}
+		}
+		update = bson.M{"$set": bson.M{"avatar": avatar}}
+	}
+
+	var previous User
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	err := userCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": userID},
+		update,
+		options.FindOneAndUpdate().SetProjection(bson.M{"avatar": 1}).SetReturnDocument(options.Before),
+	).Decode(&previous)
+	if err != nil {
+		if avatar != nil {
+			deleteAvatarFiles(ctx, avatar.ID)
+		}
+		return nil, err
+	}
+
+	if previous.Avatar != nil {
+		deleteAvatarFiles(ctx, previous.Avatar.ID)
+	}
+
+	return avatar, nil
+}
+
+// deleteAvatarFiles removes images of an avatar which is no longer used. Failures only leave garbage behind,
+// so they are logged.
+func deleteAvatarFiles(ctx context.Context, id primitive.ObjectID) {
+	for _, size := range avatarSizes {
+		if err := blobStore.Delete(ctx, avatarKey(id, size)); err != nil {
+			log.Printf(">>> Failed to delete avatar %s: %v\n", avatarKey(id, size), err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// UploadAvatarHandler godoc
+// @Summary      Upload avatar
+// @Description  JPEG, PNG or GIF up to MEDIA_MAX_SIZE bytes. The image is cropped to a square and rendered in several sizes, the previous avatar is removed.
+// @Tags         profile
+// @Accept       mpfd
+// @Produce      json
+// @Param        file   formData      file  true  "Image"
+// @Success      200  {object}  map[string]string  "Links to the avatar keyed by size in pixels"
+// @Router       /profile/avatar [put]
+func UploadAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	data, ok := readUploadedFile(w, r)
+	if !ok {
+		return
+	}
+
+	images, err := renderAvatar(data)
+	if errors.Is(err, errUnsupportedMedia) {
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	} else if errors.Is(err, errMediaTooLarge) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	avatar, err := setAvatar(context.Background(), userID, images)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(avatar)
+}
+
+// DeleteAvatarHandler godoc
+// @Summary      Remove avatar
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Router       /profile/avatar [delete]
+func DeleteAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	_, err := setAvatar(context.Background(), userContextData.ID, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Avatar removed successfully"))
+}
+
+// GetAvatarHandler godoc
+// @Summary      Get avatar image
+// @Description  Links are returned in avatar of users, no session needed
+// @Tags         profile
+// @Produce      image/jpeg
+// @Param        id   path      string  true  "ID of avatar"
+// @Param        size   path      int  true  "Size in pixels"
+// @Router       /avatars/{id}/{size}.jpg [get]
+func GetAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	idHex, fileName, found := strings.Cut(strings.TrimPrefix(r.URL.Path, "/avatars/"), "/")
+	if !found {
+		http.Error(w, "Avatar not found", http.StatusNotFound)
+		return
+	}
+
+	avatarID, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		http.Error(w, "Invalid avatar ID", http.StatusBadRequest)
+		return
+	}
+
+	size, err := strconv.Atoi(strings.TrimSuffix(fileName, ".jpg"))
+	if err != nil || !strings.HasSuffix(fileName, ".jpg") || !slices.Contains(avatarSizes, size) {
+		http.Error(w, "Avatar not found", http.StatusNotFound)
+		return
+	}
+
+	blob, err := blobStore.Get(r.Context(), avatarKey(avatarID, size))
+	if errors.Is(err, errBlobNotFound) {
+		http.Error(w, "Avatar not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	// Files of an avatar never change, a new upload gets a new ID
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	io.Copy(w, blob)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// Block hides the two users from each other: neither sees posts of the other nor can interact with them,
+// and the blocked user can't see the blocker's profile. The blocked user isn't notified.
+type Block struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Blocker   primitive.ObjectID `bson:"blocker" json:"blocker"`
+	Blocked   primitive.ObjectID `bson:"blocked" json:"blocked"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// Mute hides posts and notifications of the muted user from the muter's feeds. Unlike a block
+// it changes nothing for the muted user, who isn't told about it.
+type Mute struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Muter     primitive.ObjectID `bson:"muter" json:"muter"`
+	Muted     primitive.ObjectID `bson:"muted" json:"muted"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// blockUser blocks the user and removes follows between the two. Blocking again keeps the original block.
+func blockUser(ctx context.Context, blockerID primitive.ObjectID, blockedID primitive.ObjectID) (*Block, error) {
+	var block Block
+	blockCollection := mongoClient.Database(dbName).Collection(blocksCollectionName)
+	err := blockCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"blocker": blockerID, "blocked": blockedID},
+		bson.M{"$setOnInsert": bson.M{"createdAt": time.Now()}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&block)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := removeFollow(ctx, blockerID, blockedID); err != nil {
+		return nil, err
+	}
+	if err := removeFollow(ctx, blockedID, blockerID); err != nil {
+		return nil, err
+	}
+
+	return &block, nil
+}
+
+// muteUser mutes the user. Muting again keeps the original mute.
+func muteUser(ctx context.Context, muterID primitive.ObjectID, mutedID primitive.ObjectID) (*Mute, error) {
+	var mute Mute
+	muteCollection := mongoClient.Database(dbName).Collection(mutesCollectionName)
+	err := muteCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"muter": muterID, "muted": mutedID},
+		bson.M{"$setOnInsert": bson.M{"createdAt": time.Now()}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&mute)
+	if err != nil {
+		return nil, err
+	}
+	return &mute, nil
+}
+
+// removeFollow deletes the follow edge if there is one, updating counts and the follower's timeline.
+func removeFollow(ctx context.Context, followerID primitive.ObjectID, followeeID primitive.ObjectID) error {
+	followCollection := mongoClient.Database(dbName).Collection(followsCollectionName)
+	result, err := followCollection.DeleteOne(ctx, bson.M{"follower": followerID, "followee": followeeID})
+	if err != nil || result.DeletedCount == 0 {
+		return err
+	}
+
+	if err := updateFollowCounts(ctx, followerID, followeeID, -1); err != nil {
+		return err
+	}
+	return feedStrategy.Unfollowed(ctx, followerID, followeeID)
+}
+
+// isBlockedBetween tells whether either of the users blocked the other.
+func isBlockedBetween(ctx context.Context, userID primitive.ObjectID, otherID primitive.ObjectID) (bool, error) {
+	blockCollection := mongoClient.Database(dbName).Collection(blocksCollectionName)
+	count, err := blockCollection.CountDocuments(ctx, bson.M{"$or": bson.A{
+		bson.M{"blocker": userID, "blocked": otherID},
+		bson.M{"blocker": otherID, "blocked": userID},
+	}})
+	return count > 0, err
+}
+
+// blockedBetween returns the users among ids who blocked the user or were blocked by them.
+func blockedBetween(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	blocked := map[primitive.ObjectID]bool{}
+	if len(ids) == 0 {
+		return blocked, nil
+	}
+
+	var blocks []Block
+	blockCollection := mongoClient.Database(dbName).Collection(blocksCollectionName)
+	cursor, err := blockCollection.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"blocker": userID, "blocked": bson.M{"$in": ids}},
+		bson.M{"blocker": bson.M{"$in": ids}, "blocked": userID},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &blocks); err != nil {
+		return nil, err
+	}
+
+	for _, block := range blocks {
+		blocked[block.Blocker] = true
+		blocked[block.Blocked] = true
+	}
+	delete(blocked, userID)
+	return blocked, nil
+}
+
+// userRelations loads users blocked by the user, users blocking them and users muted by them.
+func userRelations(ctx context.Context, userID primitive.ObjectID) (blocking []primitive.ObjectID, blockedBy []primitive.ObjectID, muting []primitive.ObjectID, err error) {
+	var blocks []Block
+	blockCollection := mongoClient.Database(dbName).Collection(blocksCollectionName)
+	cursor, err := blockCollection.Find(ctx, bson.M{"$or": bson.A{bson.M{"blocker": userID}, bson.M{"blocked": userID}}})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := cursor.All(ctx, &blocks); err != nil {
+		return nil, nil, nil, err
+	}
+	for _, block := range blocks {
+		if block.Blocker == userID {
+			blocking = append(blocking, block.Blocked)
+		} else {
+			blockedBy = append(blockedBy, block.Blocker)
+		}
+	}
+
+	var mutes []Mute
+	muteCollection := mongoClient.Database(dbName).Collection(mutesCollectionName)
+	cursor, err = muteCollection.Find(ctx, bson.M{"muter": userID}, options.Find().SetProjection(bson.M{"muted": 1}))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := cursor.All(ctx, &mutes); err != nil {
+		return nil, nil, nil, err
+	}
+	for _, mute := range mutes {
+		muting = append(muting, mute.Muted)
+	}
+
+	return blocking, blockedBy, muting, nil
+}
+
+// userExists tells whether the user exists, so relations are created only with real users.
+func userExists(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	err := userCollection.FindOne(ctx, bson.M{"_id": userID}, options.FindOne().SetProjection(bson.M{"_id": 1})).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+)
+
+// BlockUserHandler godoc
+// @Summary      Block user
+// @Description  We stop seeing each other's posts and can't interact with them, follows between us are removed. The user isn't notified
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of user to block"
+// @Success      200  {object}  main.Block
+// @Router       /users/{id}/block [put]
+func BlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	blockedID, ok := relationTarget(w, r, userID, "/block")
+	if !ok {
+		return
+	}
+
+	block, err := blockUser(context.Background(), userID, blockedID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(block)
+}
+
+// UnblockUserHandler godoc
+// @Summary      Unblock user
+// @Description  Follows removed by the block aren't restored
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of blocked user"
+// @Router       /users/{id}/block [delete]
+func UnblockUserHandler(w http.ResponseWriter, r *http.Request) {
+	removeRelation(w, r, "/block", blocksCollectionName, "blocker", "blocked", "User is not blocked by you", "User unblocked successfully")
+}
+
+// MuteUserHandler godoc
+// @Summary      Mute user
+// @Description  Posts and notifications of the user are left out of my feeds, their posts are still shown when opened. The user isn't notified
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of user to mute"
+// @Success      200  {object}  main.Mute
+// @Router       /users/{id}/mute [put]
+func MuteUserHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	mutedID, ok := relationTarget(w, r, userID, "/mute")
+	if !ok {
+		return
+	}
+
+	mute, err := muteUser(context.Background(), userID, mutedID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mute)
+}
+
+// UnmuteUserHandler godoc
+// @Summary      Unmute user
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of muted user"
+// @Router       /users/{id}/mute [delete]
+func UnmuteUserHandler(w http.ResponseWriter, r *http.Request) {
+	removeRelation(w, r, "/mute", mutesCollectionName, "muter", "muted", "User is not muted by you", "User unmuted successfully")
+}
+
+// GetBlockedUsersHandler godoc
+// @Summary      Get users blocked by me
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        limit   query      int  false  "Max number of items to return"
+// @Param        before   query      string  false  "Cursor of the page with older items"
+// @Param        after   query      string  false  "Cursor of the page with newer items"
+// @Success      200  {object}  main.Page[main.UserSummary]
+// @Router       /blocks [get]
+func GetBlockedUsersHandler(w http.ResponseWriter, r *http.Request) {
+	getRelationList(w, r, blocksCollectionName, "blocker", blockKey, func(block Block) primitive.ObjectID { return block.Blocked })
+}
+
+// GetMutedUsersHandler godoc
+// @Summary      Get users muted by me
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        limit   query      int  false  "Max number of items to return"
+// @Param        before   query      string  false  "Cursor of the page with older items"
+// @Param        after   query      string  false  "Cursor of the page with newer items"
+// @Success      200  {object}  main.Page[main.UserSummary]
+// @Router       /mutes [get]
+func GetMutedUsersHandler(w http.ResponseWriter, r *http.Request) {
+	getRelationList(w, r, mutesCollectionName, "muter", muteKey, func(mute Mute) primitive.ObjectID { return mute.Muted })
+}
+
+// relationTarget parses the ID of the user to block or mute from the path and checks the user exists.
+// It writes the error response and returns false when the request can't proceed.
+func relationTarget(w http.ResponseWriter, r *http.Request, userID primitive.ObjectID, suffix string) (primitive.ObjectID, bool) {
+	targetID, err := pathObjectID(r.URL.Path, "/users/", suffix)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return primitive.NilObjectID, false
+	}
+
+	if targetID == userID {
+		http.Error(w, "You can't do that to yourself", http.StatusBadRequest)
+		return primitive.NilObjectID, false
+	}
+
+	exists, err := userExists(context.Background(), targetID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return primitive.NilObjectID, false
+	}
+	if !exists {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return primitive.NilObjectID, false
+	}
+
+	return targetID, true
+}
+
+// removeRelation deletes the block or mute of the user from the path owned by the signed in user.
+func removeRelation(w http.ResponseWriter, r *http.Request, suffix string, collectionName string, ownField string, otherField string, notFound string, success string) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	targetID, err := pathObjectID(r.URL.Path, "/users/", suffix)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	collection := mongoClient.Database(dbName).Collection(collectionName)
+	result, err := collection.DeleteOne(context.Background(), bson.M{ownField: userID, otherField: targetID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		http.Error(w, notFound, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(success))
+}
+
+// getRelationList writes a page of users blocked or muted by the signed in user, most recent first.
+// ownField is the field matching the signed in user, other picks the user to return from a relation.
+func getRelationList[T any](w http.ResponseWriter, r *http.Request, collectionName string, ownField string, key func(T) primitive.ObjectID, other func(T) primitive.ObjectID) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := page.filter("_id")
+	filter[ownField] = userID
+
+	var relations []T
+	collection := mongoClient.Database(dbName).Collection(collectionName)
+	cursor, err := collection.Find(context.Background(), filter, page.findOptions("_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := cursor.All(context.Background(), &relations); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	relationsPage := newPage(r, page, relations, key)
+
+	userIDs := make([]primitive.ObjectID, 0, len(relationsPage.Items))
+	for _, relation := range relationsPage.Items {
+		userIDs = append(userIDs, other(relation))
+	}
+
+	users, err := findUserSummaries(context.Background(), userIDs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Page[UserSummary]{Items: users, Next: relationsPage.Next, Prev: relationsPage.Prev})
+}
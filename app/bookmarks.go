@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+)
+
+const maxBookmarkCollectionNameLength = 50
+
+var errBookmarkCollectionNotFound = errors.New("bookmark collection not found")
+
+// parseBookmarkCollectionName validates a collection name from a request.
+func parseBookmarkCollectionName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxBookmarkCollectionNameLength {
+		return "", fmt.Errorf("name must be 1 to %d characters long", maxBookmarkCollectionNameLength)
+	}
+	return name, nil
+}
+
+// parseBookmarkCollectionID parses the ID of a collection of the owner, empty means no collection.
+func parseBookmarkCollectionID(ctx context.Context, owner primitive.ObjectID, hexID string) (*primitive.ObjectID, error) {
+	if hexID == "" {
+		return nil, nil
+	}
+
+	id, err := primitive.ObjectIDFromHex(hexID)
+	if err != nil {
+		return nil, errBookmarkCollectionNotFound
+	}
+
+	bookmarkCollectionCollection := mongoClient.Database(dbName).Collection(bookmarkCollectionsCollectionName)
+	count, err := bookmarkCollectionCollection.CountDocuments(ctx, bson.M{"_id": id, "owner": owner})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errBookmarkCollectionNotFound
+	}
+
+	return &id, nil
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"time"
+)
+
+type BookmarkRequestBody struct {
+	// ID of a bookmark collection to file the post in. Not filed when empty
+	CollectionID string `json:"collectionId"`
+}
+
+type BookmarkCollectionRequestBody struct {
+	Name string `json:"name"`
+}
+
+// BookmarkPostHandler godoc
+// @Summary      Bookmark post
+// @Description  Bookmarks are private, the author of the post doesn't learn about them. Bookmarking again moves the bookmark to another collection
+// @Tags         bookmarks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of post"
+// @Param        request   body      main.BookmarkRequestBody  false  "Collection"
+// @Success      200  {object}  main.Bookmark
+// @Router       /posts/{id}/bookmark [put]
+func BookmarkPostHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "/bookmark")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	// The body is optional
+	var bookmarkData BookmarkRequestBody
+	if r.ContentLength != 0 {
+		err = json.NewDecoder(r.Body).Decode(&bookmarkData)
+		if err != nil {
+			http.Error(w, "Failed to decode json", http.StatusBadRequest)
+			return
+		}
+	}
+
+	collectionID, err := parseBookmarkCollectionID(context.Background(), userID, bookmarkData.CollectionID)
+	if errors.Is(err, errBookmarkCollectionNotFound) {
+		http.Error(w, "Bookmark collection not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = findVisiblePost(context.Background(), viewer, postID)
+	if errors.Is(err, errPostNotFound) {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	update := bson.M{"$setOnInsert": bson.M{"createdAt": time.Now()}}
+	if collectionID != nil {
+		update["$set"] = bson.M{"collectionId": collectionID}
+	} else {
+		update["$unset"] = bson.M{"collectionId": ""}
+	}
+
+	var bookmark Bookmark
+	bookmarkCollection := mongoClient.Database(dbName).Collection(bookmarksCollectionName)
+	err = bookmarkCollection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"userId": userID, "postId": postID},
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&bookmark)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bookmark)
+}
+
+// RemoveBookmarkHandler godoc
+// @Summary      Remove bookmark
+// @Tags         bookmarks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of post"
+// @Router       /posts/{id}/bookmark [delete]
+func RemoveBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "/bookmark")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	bookmarkCollection := mongoClient.Database(dbName).Collection(bookmarksCollectionName)
+	result, err := bookmarkCollection.DeleteOne(context.Background(), bson.M{"userId": userID, "postId": postID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		http.Error(w, "Post is not bookmarked", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Bookmark removed successfully"))
+}
+
+// GetBookmarksHandler godoc
+// @Summary      Get bookmarked posts
+// @Description  Most recently bookmarked first
+// @Tags         bookmarks
+// @Accept       json
+// @Produce      json
+// @Param        collection   query      string  false  "ID of bookmark collection, all bookmarks when empty"
+// @Param        limit   query      int  false  "Max number of items to return"
+// @Param        before   query      string  false  "Cursor of the page with older items"
+// @Param        after   query      string  false  "Cursor of the page with newer items"
+// @Success      200  {object}  main.Page[main.PostView]
+// @Router       /bookmarks [get]
+func GetBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	collectionID, err := parseBookmarkCollectionID(context.Background(), userID, r.URL.Query().Get("collection"))
+	if errors.Is(err, errBookmarkCollectionNotFound) {
+		http.Error(w, "Bookmark collection not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	filter := page.filter("_id")
+	filter["userId"] = userID
+	if collectionID != nil {
+		filter["collectionId"] = collectionID
+	}
+
+	var bookmarks []Bookmark
+	bookmarkCollection := mongoClient.Database(dbName).Collection(bookmarksCollectionName)
+	cursor, err := bookmarkCollection.Find(context.Background(), filter, page.findOptions("_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := cursor.All(context.Background(), &bookmarks); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	bookmarksPage := newPage(r, page, bookmarks, bookmarkKey)
+
+	postIDs := make([]primitive.ObjectID, 0, len(bookmarksPage.Items))
+	for _, bookmark := range bookmarksPage.Items {
+		postIDs = append(postIDs, bookmark.PostID)
+	}
+	postsByID := findPostsByIDs(context.Background(), postIDs)
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Posts hidden since they were bookmarked are skipped
+	posts := make([]Post, 0, len(postIDs))
+	for _, postID := range postIDs {
+		if post, ok := postsByID[postID]; ok && viewer.canView(&post) {
+			posts = append(posts, post)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildPostViewsPage(context.Background(), viewer, Page[Post]{Items: posts, Next: bookmarksPage.Next, Prev: bookmarksPage.Prev}))
+}
+
+// CreateBookmarkCollectionHandler godoc
+// @Summary      Create bookmark collection
+// @Tags         bookmarks
+// @Accept       json
+// @Produce      json
+// @Param        request   body      main.BookmarkCollectionRequestBody  true  "Collection"
+// @Success      201  {object}  main.BookmarkCollection
+// @Router       /bookmarks/collections [post]
+func CreateBookmarkCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	var collectionData BookmarkCollectionRequestBody
+	err := json.NewDecoder(r.Body).Decode(&collectionData)
+	if err != nil {
+		http.Error(w, "Failed to decode json", http.StatusBadRequest)
+		return
+	}
+
+	name, err := parseBookmarkCollectionName(collectionData.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	collection := BookmarkCollection{
+		ID:        primitive.NewObjectID(),
+		Owner:     userID,
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+
+	bookmarkCollectionCollection := mongoClient.Database(dbName).Collection(bookmarkCollectionsCollectionName)
+	_, err = bookmarkCollectionCollection.InsertOne(context.Background(), collection)
+	if mongo.IsDuplicateKeyError(err) {
+		http.Error(w, "Bookmark collection with this name already exists", http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(collection)
+}
+
+// GetBookmarkCollectionsHandler godoc
+// @Summary      Get my bookmark collections
+// @Tags         bookmarks
+// @Accept       json
+// @Produce      json
+// @Param        limit   query      int  false  "Max number of items to return"
+// @Param        before   query      string  false  "Cursor of the page with older items"
+// @Param        after   query      string  false  "Cursor of the page with newer items"
+// @Success      200  {object}  main.Page[main.BookmarkCollection]
+// @Router       /bookmarks/collections [get]
+func GetBookmarkCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := page.filter("_id")
+	filter["owner"] = userID
+
+	var collections []BookmarkCollection
+	bookmarkCollectionCollection := mongoClient.Database(dbName).Collection(bookmarkCollectionsCollectionName)
+	cursor, err := bookmarkCollectionCollection.Find(context.Background(), filter, page.findOptions("_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := cursor.All(context.Background(), &collections); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newPage(r, page, collections, bookmarkCollectionKey))
+}
+
+// UpdateBookmarkCollectionHandler godoc
+// @Summary      Rename bookmark collection
+// @Tags         bookmarks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Collection ID"
+// @Param        request   body      main.BookmarkCollectionRequestBody  true  "Collection"
+// @Success      200  {object}  main.BookmarkCollection
+// @Router       /bookmarks/collections/{id} [patch]
+func UpdateBookmarkCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	collectionID, err := pathObjectID(r.URL.Path, "/bookmarks/collections/", "")
+	if err != nil {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	var collectionData BookmarkCollectionRequestBody
+	err = json.NewDecoder(r.Body).Decode(&collectionData)
+	if err != nil {
+		http.Error(w, "Failed to decode json", http.StatusBadRequest)
+		return
+	}
+
+	name, err := parseBookmarkCollectionName(collectionData.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var collection BookmarkCollection
+	bookmarkCollectionCollection := mongoClient.Database(dbName).Collection(bookmarkCollectionsCollectionName)
+	err = bookmarkCollectionCollection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": collectionID, "owner": userID},
+		bson.M{"$set": bson.M{"name": name}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&collection)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, "Bookmark collection not found", http.StatusNotFound)
+		return
+	} else if mongo.IsDuplicateKeyError(err) {
+		http.Error(w, "Bookmark collection with this name already exists", http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(collection)
+}
+
+// DeleteBookmarkCollectionHandler godoc
+// @Summary      Delete bookmark collection
+// @Description  Bookmarks of the collection are kept, just not filed anymore
+// @Tags         bookmarks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Collection ID"
+// @Router       /bookmarks/collections/{id} [delete]
+func DeleteBookmarkCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	collectionID, err := pathObjectID(r.URL.Path, "/bookmarks/collections/", "")
+	if err != nil {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	bookmarkCollectionCollection := mongoClient.Database(dbName).Collection(bookmarkCollectionsCollectionName)
+	result, err := bookmarkCollectionCollection.DeleteOne(context.Background(), bson.M{"_id": collectionID, "owner": userID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		http.Error(w, "Bookmark collection not found", http.StatusNotFound)
+		return
+	}
+
+	bookmarkCollection := mongoClient.Database(dbName).Collection(bookmarksCollectionName)
+	_, err = bookmarkCollection.UpdateMany(
+		context.Background(),
+		bson.M{"userId": userID, "collectionId": collectionID},
+		bson.M{"$unset": bson.M{"collectionId": ""}},
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Bookmark collection deleted successfully"))
+}
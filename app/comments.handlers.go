@@ -0,0 +1,473 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"time"
+)
+
+const (
+	commentsViewFlat = "flat"
+	commentsViewTree = "tree"
+	maxCommentLength = 2000
+)
+
+var (
+	errCommentNotFound = errors.New("comment not found")
+	errCommentTooLong  = fmt.Errorf("comment must be at most %d characters long", maxCommentLength)
+)
+
+type CreateCommentRequestBody struct {
+	Content string `json:"content"`
+	// ID of the comment to reply to. Empty for top level comments
+	ParentID string `json:"parentId"`
+}
+
+type UpdateCommentRequestBody struct {
+	Content string `json:"content"`
+}
+
+// CreateCommentHandler godoc
+// @Summary      Comment on post
+// @Tags         comments
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of post"
+// @Param        request   body      main.CreateCommentRequestBody  true  "Create comment data"
+// @Success      201  {object}  main.Comment
+// @Router       /posts/{id}/comments [post]
+func CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "/comments")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var createCommentData CreateCommentRequestBody
+	if !decodeContentRequest(w, r, &createCommentData) {
+		return
+	}
+
+	if createCommentData.Content == "" {
+		http.Error(w, "Content is required", http.StatusBadRequest)
+		return
+	}
+	if err := checkCommentContent(createCommentData.Content); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	post, err := findVisiblePost(context.Background(), viewer, postID)
+	if errors.Is(err, errPostNotFound) {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	comment := &Comment{
+		ID:        primitive.NewObjectID(),
+		PostID:    postID,
+		Author:    userID,
+		Content:   createCommentData.Content,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	var parent *Comment
+	if createCommentData.ParentID != "" {
+		parentID, err := primitive.ObjectIDFromHex(createCommentData.ParentID)
+		if err != nil {
+			http.Error(w, "Invalid parent comment ID", http.StatusBadRequest)
+			return
+		}
+
+		parent, err = findCommentByID(context.Background(), parentID)
+		if errors.Is(err, errCommentNotFound) || (err == nil && parent.PostID != postID) {
+			http.Error(w, "Parent comment not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rootID := parent.ID
+		if parent.RootID != nil {
+			rootID = *parent.RootID
+		}
+		comment.ParentID = &parent.ID
+		comment.RootID = &rootID
+	}
+
+	// TODO: wrap these updates into transaction
+	commentCollection := mongoClient.Database(dbName).Collection(commentsCollectionName)
+	_, err = commentCollection.InsertOne(context.Background(), comment)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	_, err = postsCollection.UpdateOne(context.Background(), bson.M{"_id": postID}, bson.M{"$inc": bson.M{"commentsCount": 1}})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = notifyAboutComment(context.Background(), post, parent, comment)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// GetCommentsHandler godoc
+// @Summary      Get comments of post
+// @Description  In the tree view pages consist of top level comments with all their replies nested
+// @Tags         comments
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of post"
+// @Param        view   query      string  false  "Shape of the list" Enums(flat, tree)
+// @Param        limit   query      int  false  "Max number of items to return"
+// @Param        before   query      string  false  "Cursor of the page with older items"
+// @Param        after   query      string  false  "Cursor of the page with newer items"
+// @Success      200  {object}  main.Page[main.Comment]
+// @Router       /posts/{id}/comments [get]
+func GetCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "/comments")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = findVisiblePost(context.Background(), viewer, postID)
+	if errors.Is(err, errPostNotFound) {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	view := r.URL.Query().Get("view")
+	if view == "" {
+		view = commentsViewTree
+	}
+	if view != commentsViewTree && view != commentsViewFlat {
+		http.Error(w, "Invalid view", http.StatusBadRequest)
+		return
+	}
+
+	filter := page.filter("_id")
+	filter["postId"] = postID
+	if view == commentsViewTree {
+		filter["parentId"] = nil
+	}
+	viewer.restrictUsers(filter, "author")
+
+	var comments []*Comment
+	commentCollection := mongoClient.Database(dbName).Collection(commentsCollectionName)
+	cursor, err := commentCollection.Find(context.Background(), filter, page.findOptions("_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := cursor.All(context.Background(), &comments); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	commentsPage := newPage(r, page, comments, commentKey)
+
+	if view == commentsViewTree {
+		err = loadReplies(context.Background(), viewer, commentsPage.Items)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(commentsPage)
+}
+
+// UpdateCommentHandler godoc
+// @Summary      Edit my comment
+// @Tags         comments
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of comment"
+// @Param        request   body      main.UpdateCommentRequestBody  true  "Update comment data"
+// @Success      200  {object}  main.Comment
+// @Router       /comments/{id} [patch]
+func UpdateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	commentID, err := pathObjectID(r.URL.Path, "/comments/", "")
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	var updateCommentData UpdateCommentRequestBody
+	if !decodeContentRequest(w, r, &updateCommentData) {
+		return
+	}
+
+	if updateCommentData.Content == "" {
+		http.Error(w, "No update fields provided", http.StatusBadRequest)
+		return
+	}
+	if err := checkCommentContent(updateCommentData.Content); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comment, err := findCommentByID(context.Background(), commentID)
+	if errors.Is(err, errCommentNotFound) || (err == nil && comment.Deleted) {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if comment.Author != userID {
+		http.Error(w, "Only author can edit the comment", http.StatusForbidden)
+		return
+	}
+
+	comment.Content = updateCommentData.Content
+	comment.UpdatedAt = time.Now()
+
+	commentCollection := mongoClient.Database(dbName).Collection(commentsCollectionName)
+	_, err = commentCollection.UpdateOne(context.Background(), bson.M{"_id": commentID}, bson.M{"$set": bson.M{
+		"content":   comment.Content,
+		"updatedAt": comment.UpdatedAt,
+	}})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comment)
+}
+
+// DeleteCommentHandler godoc
+// @Summary      Delete comment
+// @Description  Available to the author of the comment and moderators. Replies stay in place, the comment itself becomes a tombstone
+// @Tags         comments
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of comment"
+// @Router       /comments/{id} [delete]
+func DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	commentID, err := pathObjectID(r.URL.Path, "/comments/", "")
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	comment, err := findCommentByID(context.Background(), commentID)
+	if errors.Is(err, errCommentNotFound) || (err == nil && comment.Deleted) {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if comment.Author != userID {
+		moderator, err := isModerator(context.Background(), userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !moderator {
+			http.Error(w, "Only author and moderators can delete the comment", http.StatusForbidden)
+			return
+		}
+	}
+
+	// TODO: wrap these updates into transaction
+	commentCollection := mongoClient.Database(dbName).Collection(commentsCollectionName)
+	result, err := commentCollection.UpdateOne(context.Background(), bson.M{"_id": commentID, "deleted": false}, bson.M{"$set": bson.M{
+		"content":   "",
+		"deleted":   true,
+		"updatedAt": time.Now(),
+	}})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if result.ModifiedCount > 0 {
+		postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+		_, err = postsCollection.UpdateOne(context.Background(), bson.M{"_id": comment.PostID}, bson.M{"$inc": bson.M{"commentsCount": -1}})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Comment deleted successfully"))
+}
+
+// checkCommentContent rejects comments longer than maxCommentLength.
+func checkCommentContent(content string) error {
+	if len(content) > maxCommentLength {
+		return errCommentTooLong
+	}
+	return nil
+}
+
+func findCommentByID(ctx context.Context, commentID primitive.ObjectID) (*Comment, error) {
+	var comment Comment
+	commentCollection := mongoClient.Database(dbName).Collection(commentsCollectionName)
+	err := commentCollection.FindOne(ctx, bson.M{"_id": commentID}).Decode(&comment)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, errCommentNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// loadReplies fills Replies of the given top level comments with their whole threads, oldest replies first.
+// Replies the viewer can't see are left out together with the replies to them.
+func loadReplies(ctx context.Context, viewer *Viewer, roots []*Comment) error {
+	if len(roots) == 0 {
+		return nil
+	}
+
+	byID := make(map[primitive.ObjectID]*Comment, len(roots))
+	rootIDs := make([]primitive.ObjectID, 0, len(roots))
+	for _, root := range roots {
+		byID[root.ID] = root
+		rootIDs = append(rootIDs, root.ID)
+	}
+
+	var replies []*Comment
+	commentCollection := mongoClient.Database(dbName).Collection(commentsCollectionName)
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	filter := bson.M{"rootId": bson.M{"$in": rootIDs}}
+	viewer.restrictUsers(filter, "author")
+	cursor, err := commentCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return err
+	}
+	if err := cursor.All(ctx, &replies); err != nil {
+		return err
+	}
+
+	for _, reply := range replies {
+		byID[reply.ID] = reply
+	}
+	for _, reply := range replies {
+		if parent, ok := byID[*reply.ParentID]; ok {
+			parent.Replies = append(parent.Replies, reply)
+		}
+	}
+
+	return nil
+}
+
+// notifyAboutComment notifies the post author about a new comment and the parent comment author about a reply.
+// Nobody is notified about their own comments, and nobody gets two notifications about the same comment.
+func notifyAboutComment(ctx context.Context, post *Post, parent *Comment, comment *Comment) error {
+	notified := map[primitive.ObjectID]bool{comment.Author: true}
+
+	if parent != nil && !parent.Deleted && !notified[parent.Author] {
+		notified[parent.Author] = true
+		err := createNotification(ctx, &Notification{
+			UserID:    parent.Author,
+			Type:      notificationTypeReply,
+			PostID:    post.ID,
+			Actor:     comment.Author,
+			CommentID: comment.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if !notified[post.Author] {
+		err := createNotification(ctx, &Notification{
+			UserID:    post.Author,
+			Type:      notificationTypeComment,
+			PostID:    post.ID,
+			Actor:     comment.Author,
+			CommentID: comment.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const maxContentWarningLength = 200
+
+// parseContentWarning validates a content warning from a request, empty means none.
+func parseContentWarning(contentWarning string) (string, error) {
+	contentWarning = strings.TrimSpace(contentWarning)
+	if len([]rune(contentWarning)) > maxContentWarningLength {
+		return "", fmt.Errorf("content warning must be at most %d characters long", maxContentWarningLength)
+	}
+	return contentWarning, nil
+}
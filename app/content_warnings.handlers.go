@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"time"
+)
+
+type SetSensitiveRequestBody struct {
+	Sensitive bool `json:"sensitive"`
+}
+
+// SetSensitiveHandler godoc
+// @Summary      Set sensitive flag of post
+// @Description  Moderators only. The author can't change the flag afterwards
+// @Tags         posts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of post"
+// @Param        request   body      main.SetSensitiveRequestBody  true  "Flag"
+// @Success      200  {object}  main.PostView
+// @Router       /posts/{id}/sensitive [put]
+func SetSensitiveHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "/sensitive")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var sensitiveData SetSensitiveRequestBody
+	err = json.NewDecoder(r.Body).Decode(&sensitiveData)
+	if err != nil {
+		http.Error(w, "Failed to decode json", http.StatusBadRequest)
+		return
+	}
+
+	moderator, err := isModerator(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !moderator {
+		http.Error(w, "Only moderators can set the sensitive flag", http.StatusForbidden)
+		return
+	}
+
+	var post Post
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	err = postsCollection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": postID},
+		bson.M{"$set": bson.M{"sensitive": sensitiveData.Sensitive, "sensitiveLocked": true, "updatedAt": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&post)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	viewer.expandAll()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildPostView(context.Background(), viewer, &post))
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	digestMaxNotifications = 20
+	// Unread notifications are filtered for visibility this many at a time
+	digestNotificationsBatch = 500
+	digestMaxTopPosts        = 5
+)
+
+var (
+	mailer   Mailer
+	mailFrom string
+)
+
+var digestSchedules = []string{digestScheduleOff, digestScheduleDaily, digestScheduleWeekly}
+
+// digestPeriod returns the key of the period now falls into and how long a user must be inactive to get a digest.
+// The key is what makes the job idempotent: one digest per user per period.
+func digestPeriod(schedule string, now time.Time) (string, time.Duration, bool) {
+	now = now.UTC()
+	switch schedule {
+	case digestScheduleDaily:
+		return "daily-" + now.Format("2006-01-02"), 24 * time.Hour, true
+	case digestScheduleWeekly:
+		year, week := now.ISOWeek()
+		return fmt.Sprintf("weekly-%d-W%02d", year, week), 7 * 24 * time.Hour, true
+	default:
+		return "", 0, false
+	}
+}
+
+// sendDigests emails a summary of unread notifications to every user who is due for one.
+func sendDigests(ctx context.Context) error {
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+
+	filter := bson.M{
+		"digestSchedule": bson.M{"$in": []string{digestScheduleDaily, digestScheduleWeekly}},
+		"email":          bson.M{"$gt": ""},
+	}
+	cursor, err := userCollection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	now := time.Now()
+	for cursor.Next(ctx) {
+		var user User
+		if err := cursor.Decode(&user); err != nil {
+			return err
+		}
+
+		if err := sendDigest(ctx, &user, now); err != nil {
+			log.Printf(">>> Failed to send digest to user %s: %v\n", user.ID.Hex(), err)
+		}
+	}
+
+	return cursor.Err()
+}
+
+func sendDigest(ctx context.Context, user *User, now time.Time) error {
+	period, inactivity, ok := digestPeriod(user.DigestSchedule, now)
+	if !ok || user.Email == "" {
+		return nil
+	}
+	if user.LastSignInAt.After(now.Add(-inactivity)) {
+		return nil
+	}
+
+	viewer, err := loadViewer(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	unreadCount, notifications, err := unreadNotifications(ctx, viewer)
+	if err != nil {
+		return err
+	}
+
+	topPosts, err := digestTopPosts(ctx, viewer, now.Add(-inactivity))
+	if err != nil {
+		return err
+	}
+
+	if unreadCount == 0 && len(topPosts) == 0 {
+		return nil
+	}
+
+	// Claim the period before sending, so a restart or a second replica never sends it again
+	digestCollection := mongoClient.Database(dbName).Collection(digestsCollectionName)
+	digest := Digest{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		Period:    period,
+		CreatedAt: now,
+	}
+	_, err = digestCollection.InsertOne(ctx, digest)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	mail := Mail{
+		From:    mailFrom,
+		To:      user.Email,
+		Subject: digestSubject(unreadCount),
+		Body:    renderDigest(ctx, user, unreadCount, notifications, topPosts),
+	}
+	if err := mailer.Send(ctx, mail); err != nil {
+		// Release the claim so the next run can retry
+		digestCollection.DeleteOne(ctx, bson.M{"_id": digest.ID})
+		return err
+	}
+
+	return nil
+}
+
+// unreadNotifications counts unread notifications of the viewer which they can still see and returns
+// the most recent ones, so the count in a digest matches the notifications it could list.
+func unreadNotifications(ctx context.Context, viewer *Viewer) (int64, []Notification, error) {
+	notificationCollection := mongoClient.Database(dbName).Collection(notificationsCollectionName)
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetBatchSize(digestNotificationsBatch)
+	cursor, err := notificationCollection.Find(ctx, bson.M{"userId": viewer.ID, "read": false}, findOptions)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var count int64
+	recent := []Notification{}
+	batch := make([]Notification, 0, digestNotificationsBatch)
+	addBatch := func() {
+		visible := visibleNotifications(ctx, viewer, batch)
+		count += int64(len(visible))
+		recent = append(recent, visible[:min(len(visible), digestMaxNotifications-len(recent))]...)
+		batch = batch[:0]
+	}
+
+	for cursor.Next(ctx) {
+		var notification Notification
+		if err := cursor.Decode(&notification); err != nil {
+			return 0, nil, err
+		}
+		batch = append(batch, notification)
+		if len(batch) == digestNotificationsBatch {
+			addBatch()
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, nil, err
+	}
+	addBatch()
+
+	return count, recent, nil
+}
+
+// digestTopPosts returns the most liked posts written since the given time by users the viewer follows.
+func digestTopPosts(ctx context.Context, viewer *Viewer, since time.Time) ([]Post, error) {
+	if len(viewer.followees) == 0 {
+		return nil, nil
+	}
+
+	filter := bson.M{
+		"author": bson.M{"$in": viewer.followees},
+		"_id":    bson.M{"$gte": primitive.NewObjectIDFromTimestamp(since)},
+	}
+	viewer.restrictFeed(filter)
+	findOptions := options.Find().SetSort(bson.D{{Key: "likesCount", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(digestMaxTopPosts)
+
+	var posts []Post
+	postCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	cursor, err := postCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// digestSubject tells about unread notifications when there are any, a digest without them only has top posts.
+func digestSubject(unreadCount int64) string {
+	if unreadCount == 0 {
+		return "Top posts from people you follow"
+	}
+	return "You have " + unreadNotificationsText(unreadCount)
+}
+
+func unreadNotificationsText(count int64) string {
+	if count == 1 {
+		return "1 unread notification"
+	}
+	return fmt.Sprintf("%d unread notifications", count)
+}
+
+func renderDigest(ctx context.Context, user *User, unreadCount int64, notifications []Notification, topPosts []Post) string {
+	postIDs := make([]primitive.ObjectID, 0, len(notifications))
+	for _, notification := range notifications {
+		postIDs = append(postIDs, notification.PostID)
+	}
+	posts := findPostsByIDs(ctx, postIDs)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Hi %s,\n", user.Name)
+	if unreadCount > 0 {
+		fmt.Fprintf(&sb, "\nYou have %s since you last signed in.\n\n", unreadNotificationsText(unreadCount))
+	}
+	for _, notification := range notifications {
+		fmt.Fprintf(&sb, "- %s", notification.Type)
+		if post, ok := posts[notification.PostID]; ok {
+			fmt.Fprintf(&sb, " on your post \"%s\"", truncate(post.Content, 80))
+		}
+		sb.WriteString("\n")
+	}
+	if unreadCount > int64(len(notifications)) {
+		fmt.Fprintf(&sb, "...and %d more.\n", unreadCount-int64(len(notifications)))
+	}
+
+	if len(topPosts) > 0 {
+		sb.WriteString("\nTop posts from people you follow:\n")
+		for _, post := range topPosts {
+			fmt.Fprintf(&sb, "- \"%s\" (%d likes)\n", truncate(post.Content, 80), post.LikesCount)
+		}
+	}
+
+	return sb.String()
+}
+
+// findPostsByIDs loads posts by ID. Lookup errors are logged and result in missing entries.
+func findPostsByIDs(ctx context.Context, ids []primitive.ObjectID) map[primitive.ObjectID]Post {
+	posts := map[primitive.ObjectID]Post{}
+	if len(ids) == 0 {
+		return posts
+	}
+
+	postCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	cursor, err := postCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		log.Printf(">>> Failed to load posts: %v\n", err)
+		return posts
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var post Post
+		if err := cursor.Decode(&post); err == nil {
+			posts[post.ID] = post
+		}
+	}
+
+	return posts
+}
+
+func truncate(s string, maxRunes int) string {
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s
+	}
+	return string(runes[:maxRunes]) + "…"
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDigestWithoutUnreadNotifications(t *testing.T) {
+	if subject := digestSubject(0); strings.Contains(subject, "unread") {
+		t.Errorf("subject: got %q", subject)
+	}
+	if subject := digestSubject(1); subject != "You have 1 unread notification" {
+		t.Errorf("subject: got %q", subject)
+	}
+
+	user := &User{Name: "alice"}
+	body := renderDigest(context.Background(), user, 0, nil, []Post{{Content: "hello", LikesCount: 3}})
+	want := "Hi alice,\n\nTop posts from people you follow:\n- \"hello\" (3 likes)\n"
+	if body != want {
+		t.Errorf("body:\ngot  %q\nwant %q", body, want)
+	}
+}
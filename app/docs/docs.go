@@ -15,7 +15,36 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/notifications": {
+        "/avatars/{id}/{size}.jpg": {
+            "get": {
+                "description": "Links are returned in avatar of users, no session needed",
+                "produces": [
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Get avatar image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of avatar",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Size in pixels",
+                        "name": "size",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/blocks": {
             "get": {
                 "consumes": [
                     "application/json"
@@ -24,24 +53,42 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "notifications"
+                    "users"
+                ],
+                "summary": "Get users blocked by me",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get notifications",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.Notification"
-                            }
+                            "$ref": "#/definitions/main.Page-main_UserSummary"
                         }
                     }
                 }
             }
         },
-        "/posts": {
+        "/bookmarks": {
             "get": {
+                "description": "Most recently bookmarked first",
                 "consumes": [
                     "application/json"
                 ],
@@ -49,17 +96,82 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "posts"
+                    "bookmarks"
+                ],
+                "summary": "Get bookmarked posts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of bookmark collection, all bookmarks when empty",
+                        "name": "collection",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get my posts",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.Post"
-                            }
+                            "$ref": "#/definitions/main.Page-main_PostView"
+                        }
+                    }
+                }
+            }
+        },
+        "/bookmarks/collections": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "bookmarks"
+                ],
+                "summary": "Get my bookmark collections",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_BookmarkCollection"
                         }
                     }
                 }
@@ -72,32 +184,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "posts"
+                    "bookmarks"
                 ],
-                "summary": "Create post",
+                "summary": "Create bookmark collection",
                 "parameters": [
                     {
-                        "description": "Create post data",
+                        "description": "Collection",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.CreatePostRequestBody"
+                            "$ref": "#/definitions/main.BookmarkCollectionRequestBody"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.Post"
+                            "$ref": "#/definitions/main.BookmarkCollection"
                         }
                     }
                 }
             }
         },
-        "/posts/liked": {
-            "get": {
+        "/bookmarks/collections/{id}": {
+            "delete": {
+                "description": "Bookmarks of the collection are kept, just not filed anymore",
                 "consumes": [
                     "application/json"
                 ],
@@ -105,24 +218,62 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "posts"
+                    "bookmarks"
+                ],
+                "summary": "Delete bookmark collection",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "bookmarks"
+                ],
+                "summary": "Rename bookmark collection",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Collection",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.BookmarkCollectionRequestBody"
+                        }
+                    }
                 ],
-                "summary": "Get posts that I've liked",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.Post"
-                            }
+                            "$ref": "#/definitions/main.BookmarkCollection"
                         }
                     }
                 }
             }
         },
-        "/posts/{id}/like": {
-            "post": {
+        "/comments/{id}": {
+            "delete": {
+                "description": "Available to the author of the comment and moderators. Replies stay in place, the comment itself becomes a tombstone",
                 "consumes": [
                     "application/json"
                 ],
@@ -130,30 +281,62 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "posts"
+                    "comments"
                 ],
-                "summary": "Like post",
+                "summary": "Delete comment",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID of post to like",
+                        "description": "ID of comment",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Edit my comment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of comment",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Update comment data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateCommentRequestBody"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.Post"
+                            "$ref": "#/definitions/main.Comment"
                         }
                     }
                 }
             }
         },
-        "/profile": {
+        "/drafts": {
             "get": {
+                "description": "Drafts are listed newest first, published ones are removed",
                 "consumes": [
                     "application/json"
                 ],
@@ -161,19 +344,50 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "profile"
+                    "drafts"
+                ],
+                "summary": "Get my drafts",
+                "parameters": [
+                    {
+                        "enum": [
+                            "draft",
+                            "scheduled"
+                        ],
+                        "type": "string",
+                        "description": "Only drafts which are scheduled or not",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get my profile",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.User"
+                            "$ref": "#/definitions/main.Page-main_Draft"
                         }
                     }
                 }
             },
             "post": {
+                "description": "Drafts with publishAt are published by the scheduler at that time, the others with POST /drafts/{id}/publish",
                 "consumes": [
                     "application/json"
                 ],
@@ -181,30 +395,56 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "profile"
+                    "drafts"
                 ],
-                "summary": "Create profile",
+                "summary": "Create draft",
                 "parameters": [
                     {
-                        "description": "Create profile data",
+                        "description": "Draft data",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.CreteProfileRequestBody"
+                            "$ref": "#/definitions/main.CreateDraftRequestBody"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.User"
+                            "$ref": "#/definitions/main.Draft"
                         }
                     }
                 }
+            }
+        },
+        "/drafts/{id}": {
+            "delete": {
+                "description": "Cancels a scheduled post. Images attached to the draft are deleted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "drafts"
+                ],
+                "summary": "Delete draft",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Draft ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
             },
             "patch": {
+                "description": "Drafts can be edited, scheduled and unscheduled until they are being published",
                 "consumes": [
                     "application/json"
                 ],
@@ -212,17 +452,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "profile"
+                    "drafts"
                 ],
-                "summary": "Update my profile",
+                "summary": "Update draft",
                 "parameters": [
                     {
-                        "description": "Update profile data",
+                        "type": "string",
+                        "description": "Draft ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Changed draft fields",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.UpdateProfileRequestBody"
+                            "$ref": "#/definitions/main.UpdateDraftRequestBody"
                         }
                     }
                 ],
@@ -230,14 +477,15 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.User"
+                            "$ref": "#/definitions/main.Draft"
                         }
                     }
                 }
             }
         },
-        "/sign-in": {
+        "/drafts/{id}/publish": {
             "post": {
+                "description": "Publishes a draft right away, scheduled or not",
                 "consumes": [
                     "application/json"
                 ],
@@ -245,65 +493,2656 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "drafts"
                 ],
-                "summary": "Sign in",
+                "summary": "Publish draft now",
                 "parameters": [
                     {
-                        "description": "Sign in credentials",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "Draft ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.Credentials"
+                            "$ref": "#/definitions/main.PostView"
                         }
                     }
-                ],
-                "responses": {}
+                }
             }
-        }
-    },
-    "definitions": {
-        "main.CreatePostRequestBody": {
-            "type": "object",
-            "properties": {
-                "content": {
-                    "type": "string"
+        },
+        "/feed": {
+            "get": {
+                "description": "Posts of users I follow, newest first. A post reposted several times is shown once per page. In ranked mode the best of the newest posts are returned as a single page",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "feed"
+                ],
+                "summary": "Get home timeline",
+                "parameters": [
+                    {
+                        "enum": [
+                            "chronological",
+                            "ranked"
+                        ],
+                        "type": "string",
+                        "description": "Order of posts",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of posts to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older posts",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer posts",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_PostView"
+                        }
+                    }
                 }
             }
         },
-        "main.Credentials": {
-            "type": "object",
+        "/media": {
+            "post": {
+                "description": "JPEG, PNG or GIF up to MEDIA_MAX_SIZE bytes, animated GIFs are rejected. Metadata is stripped, JPEGs are turned upright by their EXIF orientation. Attach the returned ID to a post within a day or the upload is removed.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Upload image",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Image",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.MediaView"
+                        }
+                    }
+                }
+            }
+        },
+        "/media/{id}": {
+            "get": {
+                "description": "Links are signed and returned in media of posts, no session needed",
+                "produces": [
+                    "image/jpeg",
+                    "image/png"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Get image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of media",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Expiration time of the link",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signature of the link",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/media/{id}/thumbnail": {
+            "get": {
+                "description": "Links are signed and returned in media of posts, no session needed",
+                "produces": [
+                    "image/jpeg",
+                    "image/png"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Get image thumbnail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of media",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Expiration time of the link",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signature of the link",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/mentions": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Get posts mentioning me",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_PostView"
+                        }
+                    }
+                }
+            }
+        },
+        "/mutes": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get users muted by me",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_UserSummary"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Get notifications",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_Notification"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/read": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Mark notifications as read",
+                "parameters": [
+                    {
+                        "description": "Notifications to mark as read",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.MarkNotificationsReadRequestBody"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/posts": {
+            "get": {
+                "description": "The first page starts with pinned posts",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Get my posts",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_PostView"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Create post",
+                "parameters": [
+                    {
+                        "description": "Create post data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreatePostRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostView"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/liked": {
+            "get": {
+                "description": "Includes posts with any of my reactions, newest first by when I first reacted. Switching the reaction keeps the post in place",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Get posts that I've liked",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_PostView"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Get post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostView"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Available to the author of the post and moderators. Reposts and quotes of the post show a tombstone instead",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Delete post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "patch": {
+                "description": "Changes content, visibility or both. Previous content stays available in revisions of the post",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Edit my post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update post data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdatePostRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostView"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/bookmark": {
+            "put": {
+                "description": "Bookmarks are private, the author of the post doesn't learn about them. Bookmarking again moves the bookmark to another collection",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "bookmarks"
+                ],
+                "summary": "Bookmark post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Collection",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.BookmarkRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Bookmark"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "bookmarks"
+                ],
+                "summary": "Remove bookmark",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/posts/{id}/comments": {
+            "get": {
+                "description": "In the tree view pages consist of top level comments with all their replies nested",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Get comments of post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "flat",
+                            "tree"
+                        ],
+                        "type": "string",
+                        "description": "Shape of the list",
+                        "name": "view",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_Comment"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Comment on post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Create comment data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateCommentRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.Comment"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/like": {
+            "post": {
+                "description": "Same as reacting with the default reaction",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Like post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post to like",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Reaction"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/pin": {
+            "put": {
+                "description": "Pinned posts head the listings of the author posts, most recently pinned first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Pin post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of own post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Unpin post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of own post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/posts/{id}/quote": {
+            "post": {
+                "description": "Creates a new post with own content referencing the quoted one. Only public posts can be quoted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Quote post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post to quote",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Quote data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.QuotePostRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostView"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/reaction": {
+            "put": {
+                "description": "One reaction per user per post, reacting again switches the reaction",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reactions"
+                ],
+                "summary": "React to post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reaction",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ReactRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Reaction"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reactions"
+                ],
+                "summary": "Remove my reaction from post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/posts/{id}/reactions": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reactions"
+                ],
+                "summary": "Get users who reacted to post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only users who reacted with this reaction",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_UserReaction"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/repost": {
+            "post": {
+                "description": "Reposting a repost reposts its original. Only public posts can be reposted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Repost post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post to repost",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostView"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Undo repost",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of reposted post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/posts/{id}/revisions": {
+            "get": {
+                "description": "Available to the author of the post and moderators",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Get edit history of post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_PostRevision"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/sensitive": {
+            "put": {
+                "description": "Moderators only. The author can't change the flag afterwards",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Set sensitive flag of post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Flag",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.SetSensitiveRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostView"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/vote": {
+            "put": {
+                "description": "Voting again replaces the previous vote, until the poll closes",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Vote in poll",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of post with poll",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Vote",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.VoteRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostView"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Get my profile",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.User"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Create profile",
+                "parameters": [
+                    {
+                        "description": "Create profile data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreteProfileRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.User"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "JSON merge patch: missing fields are left unchanged, null clears optional fields",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Update my profile",
+                "parameters": [
+                    {
+                        "description": "Update profile data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateProfileRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.User"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/avatar": {
+            "put": {
+                "description": "JPEG, PNG or GIF up to MEDIA_MAX_SIZE bytes. The image is cropped to a square and rendered in several sizes, the previous avatar is removed.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Upload avatar",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Image",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Links to the avatar keyed by size in pixels",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Remove avatar",
+                "responses": {}
+            }
+        },
+        "/sign-in": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Sign in",
+                "parameters": [
+                    {
+                        "description": "Sign in credentials",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.Credentials"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/tags/trending": {
+            "get": {
+                "description": "Tags whose use is rising the fastest, refreshed periodically",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Get trending tags",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Max number of tags to return",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.TrendingTag"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tags/{tag}/posts": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Get posts with tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag, with or without #",
+                        "name": "tag",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_PostView"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/id/{id}": {
+            "get": {
+                "description": "Public part of the profile with recent posts visible to me",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get profile of user by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of posts to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older posts",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer posts",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PublicProfile"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/block": {
+            "put": {
+                "description": "We stop seeing each other's posts and can't interact with them, follows between us are removed. The user isn't notified",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Block user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of user to block",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Block"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Follows removed by the block aren't restored",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Unblock user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of blocked user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/{id}/follow": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Follow user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of user to follow",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.Follow"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Unfollow user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of user to unfollow",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/{id}/followers": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get followers of user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_UserSummary"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/following": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get users followed by user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_UserSummary"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/mute": {
+            "put": {
+                "description": "Posts and notifications of the user are left out of my feeds, their posts are still shown when opened. The user isn't notified",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Mute user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of user to mute",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Mute"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Unmute user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of muted user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/{id}/posts": {
+            "get": {
+                "description": "Only posts visible to me. The first page starts with pinned posts",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get posts of user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older items",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer items",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_PostView"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/suspension": {
+            "put": {
+                "description": "Moderators only. Suspended users are signed out, can't sign in and their profiles, posts and activity are hidden",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Suspend user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of user to suspend",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "delete": {
+                "description": "Moderators only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Lift suspension of user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of suspended user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/{name}": {
+            "get": {
+                "description": "Public part of the profile with recent posts visible to me. Former names of users redirect to their current names while reserved",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get profile of user by name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of posts to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with older posts",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page with newer posts",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PublicProfile"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "main.Block": {
+            "type": "object",
+            "properties": {
+                "blocked": {
+                    "type": "string"
+                },
+                "blocker": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Bookmark": {
+            "type": "object",
+            "properties": {
+                "collectionId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "postId": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.BookmarkCollection": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.BookmarkCollectionRequestBody": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.BookmarkRequestBody": {
+            "type": "object",
+            "properties": {
+                "collectionId": {
+                    "description": "ID of a bookmark collection to file the post in. Not filed when empty",
+                    "type": "string"
+                }
+            }
+        },
+        "main.Comment": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deleted": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "parentId": {
+                    "type": "string"
+                },
+                "postId": {
+                    "type": "string"
+                },
+                "replies": {
+                    "description": "Replies are only filled in the tree view",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Comment"
+                    }
+                },
+                "rootId": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreateCommentRequestBody": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "parentId": {
+                    "description": "ID of the comment to reply to. Empty for top level comments",
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreateDraftRequestBody": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "media": {
+                    "description": "IDs of uploaded images, see POST /media",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "publishAt": {
+                    "description": "RFC 3339 time to publish the draft at. The draft isn't scheduled when empty",
+                    "type": "string"
+                },
+                "visibility": {
+                    "type": "string",
+                    "default": "public",
+                    "enum": [
+                        "public",
+                        "followers",
+                        "mentioned",
+                        "private"
+                    ]
+                }
+            }
+        },
+        "main.CreatePollRequestBody": {
+            "type": "object",
+            "properties": {
+                "closesAt": {
+                    "description": "RFC 3339 time after which votes are no longer accepted",
+                    "type": "string"
+                },
+                "multiple": {
+                    "type": "boolean"
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.CreatePostRequestBody": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "contentWarning": {
+                    "description": "Shown instead of the content until the reader expands the post",
+                    "type": "string"
+                },
+                "media": {
+                    "description": "IDs of uploaded images, see POST /media",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "poll": {
+                    "$ref": "#/definitions/main.CreatePollRequestBody"
+                },
+                "sensitive": {
+                    "description": "Attached media is sensitive and hidden until the reader reveals it",
+                    "type": "boolean"
+                },
+                "visibility": {
+                    "type": "string",
+                    "default": "public",
+                    "enum": [
+                        "public",
+                        "followers",
+                        "mentioned",
+                        "private"
+                    ]
+                }
+            }
+        },
+        "main.Credentials": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreteProfileRequestBody": {
+            "type": "object",
+            "properties": {
+                "digestSchedule": {
+                    "type": "string",
+                    "enum": [
+                        "off",
+                        "daily",
+                        "weekly"
+                    ]
+                },
+                "email": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Draft": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "media": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "publishAt": {
+                    "type": "string"
+                },
+                "publishError": {
+                    "description": "Why the draft couldn't be published, it's unscheduled until edited",
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "visibility": {
+                    "type": "string",
+                    "enum": [
+                        "public",
+                        "followers",
+                        "mentioned",
+                        "private"
+                    ]
+                }
+            }
+        },
+        "main.Follow": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "followee": {
+                    "type": "string"
+                },
+                "follower": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.LinkPreview": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "image": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.MarkNotificationsReadRequestBody": {
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "description": "IDs of notifications to mark as read. All notifications are marked when empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.MediaView": {
+            "type": "object",
+            "properties": {
+                "contentType": {
+                    "type": "string"
+                },
+                "height": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "thumbnailUrl": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.Mention": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Mute": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "muted": {
+                    "type": "string"
+                },
+                "muter": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Notification": {
+            "type": "object",
+            "properties": {
+                "actorId": {
+                    "type": "string"
+                },
+                "commentId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "likedBy": {
+                    "type": "string"
+                },
+                "postId": {
+                    "type": "string"
+                },
+                "reaction": {
+                    "type": "string"
+                },
+                "read": {
+                    "type": "boolean"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Page-main_BookmarkCollection": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.BookmarkCollection"
+                    }
+                },
+                "next": {
+                    "type": "string"
+                },
+                "prev": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Page-main_Comment": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Comment"
+                    }
+                },
+                "next": {
+                    "type": "string"
+                },
+                "prev": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Page-main_Draft": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Draft"
+                    }
+                },
+                "next": {
+                    "type": "string"
+                },
+                "prev": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Page-main_Notification": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Notification"
+                    }
+                },
+                "next": {
+                    "type": "string"
+                },
+                "prev": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Page-main_PostRevision": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.PostRevision"
+                    }
+                },
+                "next": {
+                    "type": "string"
+                },
+                "prev": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Page-main_PostView": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.PostView"
+                    }
+                },
+                "next": {
+                    "type": "string"
+                },
+                "prev": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Page-main_UserReaction": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.UserReaction"
+                    }
+                },
+                "next": {
+                    "type": "string"
+                },
+                "prev": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Page-main_UserSummary": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.UserSummary"
+                    }
+                },
+                "next": {
+                    "type": "string"
+                },
+                "prev": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PollOptionView": {
+            "type": "object",
+            "properties": {
+                "text": {
+                    "type": "string"
+                },
+                "votesCount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.PollView": {
+            "type": "object",
             "properties": {
-                "password": {
+                "choices": {
+                    "description": "Options the viewer voted for, by index",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "closed": {
+                    "type": "boolean"
+                },
+                "closesAt": {
                     "type": "string"
                 },
-                "username": {
+                "multiple": {
+                    "type": "boolean"
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.PollOptionView"
+                    }
+                },
+                "votersCount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.PostRevision": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "editedBy": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "postId": {
                     "type": "string"
                 }
             }
         },
-        "main.CreteProfileRequestBody": {
+        "main.PostView": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "collapsed": {
+                    "description": "Content, media and poll are left out behind the content warning",
+                    "type": "boolean"
+                },
+                "commentsCount": {
+                    "type": "integer"
+                },
+                "content": {
+                    "description": "Markdown source",
+                    "type": "string"
+                },
+                "contentHtml": {
+                    "type": "string"
+                },
+                "contentWarning": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "post",
+                        "repost",
+                        "quote"
+                    ]
+                },
+                "likesCount": {
+                    "type": "integer"
+                },
+                "media": {
+                    "description": "Attached images",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.MediaView"
+                    }
+                },
+                "mediaHidden": {
+                    "description": "Sensitive media is left out",
+                    "type": "boolean"
+                },
+                "mentions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Mention"
+                    }
+                },
+                "original": {
+                    "description": "Original post of a repost or a quote",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.PostView"
+                        }
+                    ]
+                },
+                "originalId": {
+                    "description": "Post that is reposted or quoted",
+                    "type": "string"
+                },
+                "pinned": {
+                    "description": "Set on pinned posts heading a listing of user posts",
+                    "type": "boolean"
+                },
+                "poll": {
+                    "$ref": "#/definitions/main.PollView"
+                },
+                "preview": {
+                    "$ref": "#/definitions/main.LinkPreview"
+                },
+                "quotesCount": {
+                    "type": "integer"
+                },
+                "reactionCounts": {
+                    "description": "Likes included",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "repostsCount": {
+                    "type": "integer"
+                },
+                "sensitive": {
+                    "description": "Attached media is sensitive",
+                    "type": "boolean"
+                },
+                "sensitiveLocked": {
+                    "description": "Set by a moderator, the author can't change it then",
+                    "type": "boolean"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tombstone": {
+                    "description": "Set when the post was deleted or isn't visible to the viewer, only ID is filled then",
+                    "type": "boolean"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "visibility": {
+                    "type": "string",
+                    "enum": [
+                        "public",
+                        "followers",
+                        "mentioned",
+                        "private"
+                    ]
+                }
+            }
+        },
+        "main.PublicProfile": {
             "type": "object",
             "properties": {
                 "avatar": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "bio": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "followed": {
+                    "description": "Followed by me",
+                    "type": "boolean"
+                },
+                "followersCount": {
+                    "type": "integer"
+                },
+                "followingCount": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "links": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "location": {
                     "type": "string"
                 },
                 "name": {
                     "type": "string"
                 },
-                "password": {
+                "posts": {
+                    "description": "Recent posts visible to me, the first page starts with pinned posts",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.Page-main_PostView"
+                        }
+                    ]
+                },
+                "postsCount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.QuotePostRequestBody": {
+            "type": "object",
+            "properties": {
+                "content": {
                     "type": "string"
+                },
+                "visibility": {
+                    "type": "string",
+                    "default": "public",
+                    "enum": [
+                        "public",
+                        "followers",
+                        "mentioned",
+                        "private"
+                    ]
                 }
             }
         },
-        "main.Notification": {
+        "main.ReactRequestBody": {
+            "type": "object",
+            "properties": {
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Reaction": {
             "type": "object",
             "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
-                "likedBy": {
+                "postAuthor": {
                     "type": "string"
                 },
                 "postId": {
@@ -311,34 +3150,134 @@ const docTemplate = `{
                 },
                 "type": {
                     "type": "string"
+                },
+                "userId": {
+                    "type": "string"
                 }
             }
         },
-        "main.Post": {
+        "main.SetSensitiveRequestBody": {
             "type": "object",
             "properties": {
-                "author": {
+                "sensitive": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "main.TrendingTag": {
+            "type": "object",
+            "properties": {
+                "recentUses": {
+                    "description": "Decayed number of recent uses",
+                    "type": "number"
+                },
+                "score": {
+                    "description": "How much faster the tag is used recently than usual",
+                    "type": "number"
+                },
+                "tag": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.UpdateCommentRequestBody": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.UpdateDraftRequestBody": {
+            "type": "object",
+            "properties": {
+                "content": {
                     "type": "string"
                 },
+                "media": {
+                    "description": "IDs of uploaded images replacing the current ones. Images removed from the draft are deleted",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "publishAt": {
+                    "description": "RFC 3339 time to publish the draft at. Empty string unschedules the draft",
+                    "type": "string"
+                },
+                "visibility": {
+                    "type": "string",
+                    "enum": [
+                        "public",
+                        "followers",
+                        "mentioned",
+                        "private"
+                    ]
+                }
+            }
+        },
+        "main.UpdatePostRequestBody": {
+            "type": "object",
+            "properties": {
                 "content": {
                     "type": "string"
                 },
-                "id": {
+                "contentWarning": {
+                    "description": "Empty string removes the content warning",
                     "type": "string"
                 },
-                "likesCount": {
-                    "type": "integer"
+                "sensitive": {
+                    "type": "boolean"
+                },
+                "visibility": {
+                    "type": "string",
+                    "enum": [
+                        "public",
+                        "followers",
+                        "mentioned",
+                        "private"
+                    ]
                 }
             }
         },
         "main.UpdateProfileRequestBody": {
             "type": "object",
             "properties": {
-                "avatar": {
+                "bio": {
+                    "type": "string"
+                },
+                "digestSchedule": {
+                    "type": "string",
+                    "enum": [
+                        "off",
+                        "daily",
+                        "weekly"
+                    ]
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "expandContentWarnings": {
+                    "type": "boolean"
+                },
+                "links": {
+                    "description": "Website links, replaced as a whole",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "location": {
                     "type": "string"
                 },
                 "name": {
                     "type": "string"
+                },
+                "showSensitiveMedia": {
+                    "type": "boolean"
                 }
             }
         },
@@ -346,34 +3285,129 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "avatar": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "bio": {
+                    "type": "string"
+                },
+                "digestSchedule": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "email": {
                     "type": "string"
                 },
+                "expandContentWarnings": {
+                    "description": "Preferences for reading posts, collapsed parts are left out of post listings",
+                    "type": "boolean"
+                },
+                "followersCount": {
+                    "type": "integer"
+                },
+                "followingCount": {
+                    "type": "integer"
+                },
                 "id": {
                     "type": "string"
                 },
-                "likedPosts": {
+                "lastSignInAt": {
+                    "type": "string"
+                },
+                "links": {
+                    "description": "Websites",
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
+                "location": {
+                    "type": "string"
+                },
                 "name": {
                     "type": "string"
                 },
+                "nameChangedAt": {
+                    "type": "string"
+                },
                 "notifications": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
-                "password": {
-                    "type": "string"
+                "pinnedPosts": {
+                    "description": "Most recently pinned first",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
                 "posts": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
+                },
+                "role": {
+                    "type": "string"
+                },
+                "showSensitiveMedia": {
+                    "type": "boolean"
+                },
+                "suspendedAt": {
+                    "description": "Set by a moderator",
+                    "type": "string"
+                }
+            }
+        },
+        "main.UserReaction": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/main.UserSummary"
+                }
+            }
+        },
+        "main.UserSummary": {
+            "type": "object",
+            "properties": {
+                "avatar": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.VoteRequestBody": {
+            "type": "object",
+            "properties": {
+                "choices": {
+                    "description": "Indexes of chosen options, more than one only in multiple choice polls",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 }
             }
         }
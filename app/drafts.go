@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+// A publishing attempt which hasn't finished within this time is considered crashed and the draft is claimed again
+const draftClaimLease = 5 * time.Minute
+
+var (
+	errDraftNotFound   = errors.New("draft not found")
+	errDraftPublishing = errors.New("draft is being published")
+	errPublishAtPassed = errors.New("publishAt must be in the future")
+)
+
+// editableDraftFilter matches a draft of the author which no publishing attempt has claimed yet.
+func editableDraftFilter(id primitive.ObjectID, author primitive.ObjectID) bson.M {
+	return bson.M{"_id": id, "author": author, "postId": nil}
+}
+
+// draftLookupError tells why a draft of the author couldn't be edited or claimed.
+func draftLookupError(ctx context.Context, id primitive.ObjectID, author primitive.ObjectID) error {
+	draftCollection := mongoClient.Database(dbName).Collection(draftsCollectionName)
+	count, err := draftCollection.CountDocuments(ctx, bson.M{"_id": id, "author": author})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errDraftPublishing
+	}
+	return errDraftNotFound
+}
+
+// checkDraftContent rejects content of a draft which is too long. Mentions are resolved on publishing,
+// their links add little to the length.
+func checkDraftContent(content string) error {
+	if err := checkContentSource(content); err != nil {
+		return err
+	}
+	return checkContentLength(renderContent(content, nil))
+}
+
+// parsePublishAt validates the scheduled time from a request, empty means not scheduled.
+func parsePublishAt(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	publishAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errors.New("publishAt must be an RFC 3339 time")
+	}
+	if !publishAt.After(time.Now()) {
+		return nil, errPublishAtPassed
+	}
+
+	return &publishAt, nil
+}
+
+// claimDraft reserves a draft matching the filter for publishing, so that only one replica publishes it.
+// The post ID is reserved by the first claim and kept by the next ones.
+func claimDraft(ctx context.Context, filter bson.M) (*Draft, error) {
+	now := time.Now()
+	filter["$or"] = bson.A{
+		bson.M{"claimedUntil": nil},
+		bson.M{"claimedUntil": bson.M{"$lt": now}},
+	}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"claimedUntil": now.Add(draftClaimLease),
+		"postId":       bson.M{"$ifNull": bson.A{"$postId", primitive.NewObjectID()}},
+	}}}}
+
+	var draft Draft
+	draftCollection := mongoClient.Database(dbName).Collection(draftsCollectionName)
+	err := draftCollection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&draft)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, errDraftNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &draft, nil
+}
+
+// publishDraft turns a claimed draft into a post and deletes the draft. It's safe to run again after
+// an interrupted attempt: the post keeps the ID reserved by the claim and what follows from saving it is repeated.
+// A draft which can't be published as it is gets unscheduled with the reason, so it isn't retried until edited.
+func publishDraft(ctx context.Context, draft *Draft) (*Post, error) {
+	now := time.Now()
+	post := &Post{
+		ID:         *draft.PostID,
+		Kind:       postKindPost,
+		Visibility: draft.Visibility,
+		Author:     draft.Author,
+		Content:    draft.Content,
+		Media:      draft.Media,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	// Mentions are resolved again, the content may have grown too long since the draft was saved
+	err := preparePost(ctx, post)
+	if errors.Is(err, errContentTooLong) {
+		if err := unscheduleDraft(ctx, draft, err); err != nil {
+			return nil, err
+		}
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	mediaCollection := mongoClient.Database(dbName).Collection(mediaCollectionName)
+	_, err = mediaCollection.UpdateMany(ctx, bson.M{"postId": draft.ID}, bson.M{"$set": bson.M{"postId": post.ID}})
+	if err != nil {
+		return nil, err
+	}
+
+	err = insertPost(ctx, post)
+	if mongo.IsDuplicateKeyError(err) {
+		// An earlier attempt saved the post and may have stopped in the middle of what follows
+		post, err = findPostByID(ctx, post.ID)
+		if err == nil {
+			err = finishPost(ctx, post)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	draftCollection := mongoClient.Database(dbName).Collection(draftsCollectionName)
+	_, err = draftCollection.DeleteOne(ctx, bson.M{"_id": draft.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// unscheduleDraft releases the claim on a draft which failed to publish for good and records why.
+// Media moved to the reserved post by an earlier attempt is given back to the draft.
+func unscheduleDraft(ctx context.Context, draft *Draft, reason error) error {
+	mediaCollection := mongoClient.Database(dbName).Collection(mediaCollectionName)
+	_, err := mediaCollection.UpdateMany(ctx, bson.M{"postId": *draft.PostID}, bson.M{"$set": bson.M{"postId": draft.ID}})
+	if err != nil {
+		return err
+	}
+
+	draftCollection := mongoClient.Database(dbName).Collection(draftsCollectionName)
+	_, err = draftCollection.UpdateOne(ctx, bson.M{"_id": draft.ID}, bson.M{
+		"$set":   bson.M{"publishError": reason.Error()},
+		"$unset": bson.M{"publishAt": "", "postId": "", "claimedUntil": ""},
+	})
+	return err
+}
+
+// publishScheduledPosts publishes all drafts which are due. Drafts which failed to publish are retried once
+// their claim expires, unless they can't be published as they are.
+func publishScheduledPosts(ctx context.Context) error {
+	for {
+		draft, err := claimDraft(ctx, bson.M{"publishAt": bson.M{"$lte": time.Now()}})
+		if errors.Is(err, errDraftNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := publishDraft(ctx, draft); err != nil {
+			log.Printf(">>> Failed to publish draft %s: %v\n", draft.ID.Hex(), err)
+		}
+	}
+}
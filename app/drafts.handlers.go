@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"slices"
+	"time"
+)
+
+type CreateDraftRequestBody struct {
+	Content    string `json:"content"`
+	Visibility string `json:"visibility" enums:"public,followers,mentioned,private" default:"public"`
+	// IDs of uploaded images, see POST /media
+	Media []string `json:"media"`
+	// RFC 3339 time to publish the draft at. The draft isn't scheduled when empty
+	PublishAt string `json:"publishAt"`
+}
+
+// UpdateDraftRequestBody changes only the fields present in the request.
+type UpdateDraftRequestBody struct {
+	Content    *string `json:"content"`
+	Visibility *string `json:"visibility" enums:"public,followers,mentioned,private"`
+	// IDs of uploaded images replacing the current ones. Images removed from the draft are deleted
+	Media []string `json:"media"`
+	// RFC 3339 time to publish the draft at. Empty string unschedules the draft
+	PublishAt *string `json:"publishAt"`
+}
+
+// CreateDraftHandler godoc
+// @Summary      Create draft
+// @Description  Drafts with publishAt are published by the scheduler at that time, the others with POST /drafts/{id}/publish
+// @Tags         drafts
+// @Accept       json
+// @Produce      json
+// @Param        request   body      main.CreateDraftRequestBody  true  "Draft data"
+// @Success      201  {object}  main.Draft
+// @Router       /drafts [post]
+func CreateDraftHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	var createDraftData CreateDraftRequestBody
+	if !decodeContentRequest(w, r, &createDraftData) {
+		return
+	}
+
+	visibility, err := parseVisibility(createDraftData.Visibility)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mediaIDs, err := parseMediaIDs(createDraftData.Media)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := checkDraftContent(createDraftData.Content); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	publishAt, err := parsePublishAt(createDraftData.PublishAt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	draft := &Draft{
+		ID:         primitive.NewObjectID(),
+		Author:     userID,
+		Content:    createDraftData.Content,
+		Visibility: visibility,
+		Media:      mediaIDs,
+		PublishAt:  publishAt,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	err = attachMedia(context.Background(), userID, draft.ID, mediaIDs)
+	if errors.Is(err, errMediaNotAttachable) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	draftCollection := mongoClient.Database(dbName).Collection(draftsCollectionName)
+	_, err = draftCollection.InsertOne(context.Background(), draft)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(draft)
+}
+
+// GetDraftsHandler godoc
+// @Summary      Get my drafts
+// @Description  Drafts are listed newest first, published ones are removed
+// @Tags         drafts
+// @Accept       json
+// @Produce      json
+// @Param        status   query      string  false  "Only drafts which are scheduled or not"  Enums(draft, scheduled)
+// @Param        limit   query      int  false  "Max number of items to return"
+// @Param        before   query      string  false  "Cursor of the page with older items"
+// @Param        after   query      string  false  "Cursor of the page with newer items"
+// @Success      200  {object}  main.Page[main.Draft]
+// @Router       /drafts [get]
+func GetDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := page.filter("_id")
+	filter["author"] = userID
+
+	switch r.URL.Query().Get("status") {
+	case "":
+	case "draft":
+		filter["publishAt"] = nil
+	case "scheduled":
+		filter["publishAt"] = bson.M{"$ne": nil}
+	default:
+		http.Error(w, "status must be one of draft, scheduled", http.StatusBadRequest)
+		return
+	}
+
+	var drafts []Draft
+	draftCollection := mongoClient.Database(dbName).Collection(draftsCollectionName)
+	cursor, err := draftCollection.Find(context.Background(), filter, page.findOptions("_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := cursor.All(context.Background(), &drafts); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newPage(r, page, drafts, draftKey))
+}
+
+// UpdateDraftHandler godoc
+// @Summary      Update draft
+// @Description  Drafts can be edited, scheduled and unscheduled until they are being published
+// @Tags         drafts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Draft ID"
+// @Param        request   body      main.UpdateDraftRequestBody  true  "Changed draft fields"
+// @Success      200  {object}  main.Draft
+// @Router       /drafts/{id} [patch]
+func UpdateDraftHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	draftID, err := pathObjectID(r.URL.Path, "/drafts/", "")
+	if err != nil {
+		http.Error(w, "Invalid draft ID", http.StatusBadRequest)
+		return
+	}
+
+	var updateDraftData UpdateDraftRequestBody
+	if !decodeContentRequest(w, r, &updateDraftData) {
+		return
+	}
+
+	var draft Draft
+	draftCollection := mongoClient.Database(dbName).Collection(draftsCollectionName)
+	err = draftCollection.FindOne(context.Background(), editableDraftFilter(draftID, userID)).Decode(&draft)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeDraftLookupError(w, draftLookupError(context.Background(), draftID, userID))
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	set := bson.M{"updatedAt": time.Now()}
+	unset := bson.M{"publishError": ""}
+
+	if updateDraftData.Content != nil {
+		if err := checkDraftContent(*updateDraftData.Content); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		set["content"] = *updateDraftData.Content
+	}
+
+	if updateDraftData.Visibility != nil {
+		visibility, err := parseVisibility(*updateDraftData.Visibility)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		set["visibility"] = visibility
+	}
+
+	if updateDraftData.PublishAt != nil {
+		publishAt, err := parsePublishAt(*updateDraftData.PublishAt)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if publishAt != nil {
+			set["publishAt"] = publishAt
+		} else {
+			unset["publishAt"] = ""
+		}
+	}
+
+	// Media is replaced only when present in the request, an empty list removes all images
+	var addedMedia, removedMedia []primitive.ObjectID
+	if updateDraftData.Media != nil {
+		mediaIDs, err := parseMediaIDs(updateDraftData.Media)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, id := range mediaIDs {
+			if !slices.Contains(draft.Media, id) {
+				addedMedia = append(addedMedia, id)
+			}
+		}
+		for _, id := range draft.Media {
+			if !slices.Contains(mediaIDs, id) {
+				removedMedia = append(removedMedia, id)
+			}
+		}
+
+		err = attachMedia(context.Background(), userID, draft.ID, addedMedia)
+		if errors.Is(err, errMediaNotAttachable) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(mediaIDs) > 0 {
+			set["media"] = mediaIDs
+		} else {
+			unset["media"] = ""
+		}
+	}
+
+	// Editing a draft which failed to publish clears the error
+	update := bson.M{"$set": set, "$unset": unset}
+
+	// The draft may have been claimed for publishing since it was loaded
+	err = draftCollection.FindOneAndUpdate(
+		context.Background(),
+		editableDraftFilter(draftID, userID),
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&draft)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		mediaCollection := mongoClient.Database(dbName).Collection(mediaCollectionName)
+		_, err = mediaCollection.UpdateMany(
+			context.Background(),
+			bson.M{"_id": bson.M{"$in": addedMedia}, "postId": draft.ID},
+			bson.M{"$unset": bson.M{"postId": ""}},
+		)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeDraftLookupError(w, draftLookupError(context.Background(), draftID, userID))
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if len(removedMedia) > 0 {
+		err = deleteMedia(context.Background(), bson.M{"_id": bson.M{"$in": removedMedia}, "postId": draft.ID})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(draft)
+}
+
+// DeleteDraftHandler godoc
+// @Summary      Delete draft
+// @Description  Cancels a scheduled post. Images attached to the draft are deleted
+// @Tags         drafts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Draft ID"
+// @Router       /drafts/{id} [delete]
+func DeleteDraftHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	draftID, err := pathObjectID(r.URL.Path, "/drafts/", "")
+	if err != nil {
+		http.Error(w, "Invalid draft ID", http.StatusBadRequest)
+		return
+	}
+
+	draftCollection := mongoClient.Database(dbName).Collection(draftsCollectionName)
+	result, err := draftCollection.DeleteOne(context.Background(), editableDraftFilter(draftID, userID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		writeDraftLookupError(w, draftLookupError(context.Background(), draftID, userID))
+		return
+	}
+
+	err = deleteMedia(context.Background(), bson.M{"postId": draftID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Draft deleted successfully"))
+}
+
+// PublishDraftHandler godoc
+// @Summary      Publish draft now
+// @Description  Publishes a draft right away, scheduled or not
+// @Tags         drafts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Draft ID"
+// @Success      201  {object}  main.PostView
+// @Router       /drafts/{id}/publish [post]
+func PublishDraftHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	draftID, err := pathObjectID(r.URL.Path, "/drafts/", "/publish")
+	if err != nil {
+		http.Error(w, "Invalid draft ID", http.StatusBadRequest)
+		return
+	}
+
+	draft, err := claimDraft(context.Background(), bson.M{"_id": draftID, "author": userID})
+	if errors.Is(err, errDraftNotFound) {
+		writeDraftLookupError(w, draftLookupError(context.Background(), draftID, userID))
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	post, err := publishDraft(context.Background(), draft)
+	if errors.Is(err, errContentTooLong) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(buildPostView(context.Background(), viewer, post))
+}
+
+func writeDraftLookupError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errDraftNotFound):
+		http.Error(w, "Draft not found", http.StatusNotFound)
+	case errors.Is(err, errDraftPublishing):
+		http.Error(w, "Draft is being published", http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
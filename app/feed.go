@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	feedFanOutOnRead  = "read"
+	feedFanOutOnWrite = "write"
+
+	// How many posts of a newly followed user are copied to the follower's timeline
+	fanOutFollowBackfill = 100
+	fanOutBatchSize      = 1000
+)
+
+var feedStrategy FeedStrategy
+
+// FeedStrategy builds home timelines. Implementations differ in when the work is done:
+// on every post write, or on every timeline read.
+type FeedStrategy interface {
+	PostCreated(ctx context.Context, post *Post) error
+	Followed(ctx context.Context, followerID primitive.ObjectID, followeeID primitive.ObjectID) error
+	Unfollowed(ctx context.Context, followerID primitive.ObjectID, followeeID primitive.ObjectID) error
+	// Timeline returns posts of users followed by the viewer, newest first. Posts hidden from the viewer
+	// and posts of muted users are skipped.
+	// One extra post is returned when there are more.
+	Timeline(ctx context.Context, viewer *Viewer, page pageParams) ([]Post, error)
+}
+
+func newFeedStrategy(kind string) (FeedStrategy, error) {
+	switch kind {
+	case feedFanOutOnRead, "":
+		return fanOutOnRead{}, nil
+	case feedFanOutOnWrite:
+		return fanOutOnWrite{}, nil
+	default:
+		return nil, fmt.Errorf("unknown feed fan-out strategy: %s", kind)
+	}
+}
+
+// fanOutOnRead stores nothing and merges posts of all followees on every read.
+// Cheap writes, but reads get slower the more users the reader follows.
+type fanOutOnRead struct{}
+
+func (fanOutOnRead) PostCreated(context.Context, *Post) error { return nil }
+
+func (fanOutOnRead) Followed(context.Context, primitive.ObjectID, primitive.ObjectID) error {
+	return nil
+}
+
+func (fanOutOnRead) Unfollowed(context.Context, primitive.ObjectID, primitive.ObjectID) error {
+	return nil
+}
+
+func (fanOutOnRead) Timeline(ctx context.Context, viewer *Viewer, page pageParams) ([]Post, error) {
+	if len(viewer.followees) == 0 {
+		return nil, nil
+	}
+
+	filter := page.filter("_id")
+	filter["author"] = bson.M{"$in": viewer.followees}
+	viewer.restrictFeed(filter)
+
+	var posts []Post
+	postCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	cursor, err := postCollection.Find(ctx, filter, page.findOptions("_id"))
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// TimelineEntry is a post copied to the timeline of one of its author's followers.
+type TimelineEntry struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty"`
+	Owner  primitive.ObjectID `bson:"owner"`
+	PostID primitive.ObjectID `bson:"postId"`
+	Author primitive.ObjectID `bson:"author"`
+}
+
+// fanOutOnWrite copies every new post to the timelines of all followers of its author.
+// Reads are a single indexed query, but writes of users with many followers get expensive.
+type fanOutOnWrite struct{}
+
+func (fanOutOnWrite) PostCreated(ctx context.Context, post *Post) error {
+	followCollection := mongoClient.Database(dbName).Collection(followsCollectionName)
+	projection := options.Find().SetProjection(bson.M{"follower": 1}).SetBatchSize(fanOutBatchSize)
+	cursor, err := followCollection.Find(ctx, bson.M{"followee": post.Author}, projection)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	entries := make([]interface{}, 0, fanOutBatchSize)
+	for cursor.Next(ctx) {
+		var follow Follow
+		if err := cursor.Decode(&follow); err != nil {
+			return err
+		}
+
+		entries = append(entries, TimelineEntry{
+			ID:     primitive.NewObjectID(),
+			Owner:  follow.Follower,
+			PostID: post.ID,
+			Author: post.Author,
+		})
+		if len(entries) == fanOutBatchSize {
+			if err := insertTimelineEntries(ctx, entries); err != nil {
+				return err
+			}
+			entries = entries[:0]
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	return insertTimelineEntries(ctx, entries)
+}
+
+func (fanOutOnWrite) Followed(ctx context.Context, followerID primitive.ObjectID, followeeID primitive.ObjectID) error {
+	var posts []Post
+	postCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(fanOutFollowBackfill).
+		SetProjection(bson.M{"_id": 1, "author": 1})
+	cursor, err := postCollection.Find(ctx, bson.M{"author": followeeID}, findOptions)
+	if err != nil {
+		return err
+	}
+	if err := cursor.All(ctx, &posts); err != nil {
+		return err
+	}
+
+	entries := make([]interface{}, 0, len(posts))
+	for _, post := range posts {
+		entries = append(entries, TimelineEntry{
+			ID:     primitive.NewObjectID(),
+			Owner:  followerID,
+			PostID: post.ID,
+			Author: post.Author,
+		})
+	}
+
+	return insertTimelineEntries(ctx, entries)
+}
+
+func (fanOutOnWrite) Unfollowed(ctx context.Context, followerID primitive.ObjectID, followeeID primitive.ObjectID) error {
+	timelineCollection := mongoClient.Database(dbName).Collection(timelinesCollectionName)
+	_, err := timelineCollection.DeleteMany(ctx, bson.M{"owner": followerID, "author": followeeID})
+	return err
+}
+
+// Timeline joins entries with their posts before limiting, so entries of deleted and hidden posts don't shorten pages.
+func (fanOutOnWrite) Timeline(ctx context.Context, viewer *Viewer, page pageParams) ([]Post, error) {
+	filter := page.filter("postId")
+	filter["owner"] = viewer.ID
+
+	postFilter := bson.M{}
+	viewer.restrictFeed(postFilter)
+
+	timelineCollection := mongoClient.Database(dbName).Collection(timelinesCollectionName)
+	cursor, err := timelineCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: page.sort("postId")}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         postsCollectionName,
+			"localField":   "postId",
+			"foreignField": "_id",
+			"as":           "post",
+		}}},
+		{{Key: "$unwind", Value: "$post"}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$post"}}},
+		{{Key: "$match", Value: postFilter}},
+		{{Key: "$limit", Value: page.limit + 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var posts []Post
+	if err := cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// insertTimelineEntries skips entries that are already present in the timeline.
+func insertTimelineEntries(ctx context.Context, entries []interface{}) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	timelineCollection := mongoClient.Database(dbName).Collection(timelinesCollectionName)
+	_, err := timelineCollection.InsertMany(ctx, entries, options.InsertMany().SetOrdered(false))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	feedModeChronological = "chronological"
+	feedModeRanked        = "ranked"
+)
+
+// GetFeedHandler godoc
+// @Summary      Get home timeline
+// @Description  Posts of users I follow, newest first. A post reposted several times is shown once per page. In ranked mode the best of the newest posts are returned as a single page
+// @Tags         feed
+// @Accept       json
+// @Produce      json
+// @Param        mode   query      string  false  "Order of posts" Enums(chronological, ranked)
+// @Param        limit   query      int  false  "Max number of posts to return"
+// @Param        before   query      string  false  "Cursor of the page with older posts"
+// @Param        after   query      string  false  "Cursor of the page with newer posts"
+// @Success      200  {object}  main.Page[main.PostView]
+// @Router       /feed [get]
+func GetFeedHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == feedModeRanked {
+		posts, err := rankedTimeline(context.Background(), viewer, page.limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Page[PostView]{Items: buildPostViews(context.Background(), viewer, posts)})
+		return
+	} else if mode != "" && mode != feedModeChronological {
+		http.Error(w, "Invalid feed mode", http.StatusBadRequest)
+		return
+	}
+
+	posts, err := feedStrategy.Timeline(context.Background(), viewer, page)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	feedPage := newPage(r, page, posts, postKey)
+	feedPage.Items = dedupeReposts(feedPage.Items)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildPostViewsPage(context.Background(), viewer, feedPage))
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"time"
+)
+
+// FollowUserHandler godoc
+// @Summary      Follow user
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of user to follow"
+// @Success      201  {object}  main.Follow
+// @Router       /users/{id}/follow [post]
+func FollowUserHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	followeeID, err := pathObjectID(r.URL.Path, "/users/", "/follow")
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if followeeID == userID {
+		http.Error(w, "You can't follow yourself", http.StatusBadRequest)
+		return
+	}
+
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	err = userCollection.FindOne(context.Background(), bson.M{"_id": followeeID}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// TODO: wrap these updates into transaction
+	follow, err := insertFollow(context.Background(), viewer, followeeID)
+	if errors.Is(err, errFollowNotAllowed) {
+		http.Error(w, "You can't follow this user", http.StatusForbidden)
+		return
+	} else if mongo.IsDuplicateKeyError(err) {
+		http.Error(w, "User is already followed by you", http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = updateFollowCounts(context.Background(), userID, followeeID, 1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = feedStrategy.Followed(context.Background(), userID, followeeID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = createNotification(context.Background(), &Notification{
+		UserID: followeeID,
+		Type:   notificationTypeFollow,
+		Actor:  userID,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(follow)
+}
+
+// UnfollowUserHandler godoc
+// @Summary      Unfollow user
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of user to unfollow"
+// @Router       /users/{id}/follow [delete]
+func UnfollowUserHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	followeeID, err := pathObjectID(r.URL.Path, "/users/", "/follow")
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	followCollection := mongoClient.Database(dbName).Collection(followsCollectionName)
+	result, err := followCollection.DeleteOne(context.Background(), bson.M{"follower": userID, "followee": followeeID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		http.Error(w, "User is not followed by you", http.StatusNotFound)
+		return
+	}
+
+	err = updateFollowCounts(context.Background(), userID, followeeID, -1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = feedStrategy.Unfollowed(context.Background(), userID, followeeID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("User unfollowed successfully"))
+}
+
+// GetFollowersHandler godoc
+// @Summary      Get followers of user
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of user"
+// @Param        limit   query      int  false  "Max number of items to return"
+// @Param        before   query      string  false  "Cursor of the page with older items"
+// @Param        after   query      string  false  "Cursor of the page with newer items"
+// @Success      200  {object}  main.Page[main.UserSummary]
+// @Router       /users/{id}/followers [get]
+func GetFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	getFollowList(w, r, "/followers", "followee", func(follow Follow) primitive.ObjectID { return follow.Follower })
+}
+
+// GetFollowingHandler godoc
+// @Summary      Get users followed by user
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of user"
+// @Param        limit   query      int  false  "Max number of items to return"
+// @Param        before   query      string  false  "Cursor of the page with older items"
+// @Param        after   query      string  false  "Cursor of the page with newer items"
+// @Success      200  {object}  main.Page[main.UserSummary]
+// @Router       /users/{id}/following [get]
+func GetFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	getFollowList(w, r, "/following", "follower", func(follow Follow) primitive.ObjectID { return follow.Followee })
+}
+
+// getFollowList writes a page of users on the other side of the user's follow edges, newest edges first.
+// ownField is the edge field matching the user from the path, other picks the user to return from an edge.
+func getFollowList(w http.ResponseWriter, r *http.Request, suffix string, ownField string, other func(Follow) primitive.ObjectID) {
+	userID, err := pathObjectID(r.URL.Path, "/users/", suffix)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := page.filter("_id")
+	filter[ownField] = userID
+
+	var follows []Follow
+	followCollection := mongoClient.Database(dbName).Collection(followsCollectionName)
+	cursor, err := followCollection.Find(context.Background(), filter, page.findOptions("_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := cursor.All(context.Background(), &follows); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	followsPage := newPage(r, page, follows, followKey)
+
+	userIDs := make([]primitive.ObjectID, 0, len(followsPage.Items))
+	for _, follow := range followsPage.Items {
+		userIDs = append(userIDs, other(follow))
+	}
+
+	users, err := findUserSummaries(context.Background(), userIDs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Page[UserSummary]{Items: users, Next: followsPage.Next, Prev: followsPage.Prev})
+}
+
+var errFollowNotAllowed = errors.New("user can't be followed")
+
+// insertFollow makes the viewer follow the user when the viewer may. The viewer's blocks were loaded before
+// the follow is inserted, so blocks are checked again after it: a block made in between is either seen here
+// or made after the insert, and then blockUser removes the follow.
+func insertFollow(ctx context.Context, viewer *Viewer, followeeID primitive.ObjectID) (*Follow, error) {
+	if !viewer.canFollow(followeeID) {
+		return nil, errFollowNotAllowed
+	}
+
+	follow := &Follow{
+		ID:        primitive.NewObjectID(),
+		Follower:  viewer.ID,
+		Followee:  followeeID,
+		CreatedAt: time.Now(),
+	}
+
+	followCollection := mongoClient.Database(dbName).Collection(followsCollectionName)
+	_, err := followCollection.InsertOne(ctx, follow)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := isBlockedBetween(ctx, viewer.ID, followeeID)
+	if err == nil && !blocked {
+		return follow, nil
+	}
+	if _, deleteErr := followCollection.DeleteOne(ctx, bson.M{"_id": follow.ID}); deleteErr != nil {
+		return nil, deleteErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, errFollowNotAllowed
+}
+
+func updateFollowCounts(ctx context.Context, followerID primitive.ObjectID, followeeID primitive.ObjectID, delta int) error {
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+
+	_, err := userCollection.UpdateOne(ctx, bson.M{"_id": followerID}, bson.M{"$inc": bson.M{"followingCount": delta}})
+	if err != nil {
+		return err
+	}
+
+	_, err = userCollection.UpdateOne(ctx, bson.M{"_id": followeeID}, bson.M{"$inc": bson.M{"followersCount": delta}})
+	return err
+}
+
+// findUserSummaries loads users by ID preserving the order of ids. Unknown IDs are skipped.
+func findUserSummaries(ctx context.Context, ids []primitive.ObjectID) ([]UserSummary, error) {
+	users := []UserSummary{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	projection := options.Find().SetProjection(bson.M{"name": 1, "displayName": 1, "avatar": 1})
+	cursor, err := userCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, projection)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []UserSummary
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]UserSummary, len(found))
+	for _, user := range found {
+		byID[user.ID] = user
+	}
+	for _, id := range ids {
+		if user, ok := byID[id]; ok {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+// followeeIDs returns IDs of all users followed by the user.
+func followeeIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	followCollection := mongoClient.Database(dbName).Collection(followsCollectionName)
+	projection := options.Find().SetProjection(bson.M{"followee": 1})
+	cursor, err := followCollection.Find(ctx, bson.M{"follower": userID}, projection)
+	if err != nil {
+		return nil, err
+	}
+
+	var follows []Follow
+	if err := cursor.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(follows))
+	for _, follow := range follows {
+		ids = append(ids, follow.Followee)
+	}
+
+	return ids, nil
+}
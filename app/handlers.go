@@ -7,6 +7,8 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"io"
+	"log"
 	"net/http"
 	"slices"
 	"strings"
@@ -14,18 +16,50 @@ import (
 )
 
 type CreteProfileRequestBody struct {
-	Name     string `json:"name"`
-	Password string `json:"password"`
-	Avatar   string `json:"avatar"`
+	Name           string `json:"name"`
+	Password       string `json:"password"`
+	Email          string `json:"email"`
+	DigestSchedule string `json:"digestSchedule" enums:"off,daily,weekly"`
 }
 
+// UpdateProfileRequestBody is a JSON merge patch of the profile: missing fields are left unchanged,
+// null or empty values clear optional fields.
 type UpdateProfileRequestBody struct {
-	Name   string `json:"name"`
-	Avatar string `json:"avatar"`
+	Name                  patchField[string] `json:"name" swaggertype:"string"`
+	Email                 patchField[string] `json:"email" swaggertype:"string"`
+	DigestSchedule        patchField[string] `json:"digestSchedule" swaggertype:"string" enums:"off,daily,weekly"`
+	ExpandContentWarnings patchField[bool]   `json:"expandContentWarnings" swaggertype:"boolean"`
+	ShowSensitiveMedia    patchField[bool]   `json:"showSensitiveMedia" swaggertype:"boolean"`
+	DisplayName           patchField[string] `json:"displayName" swaggertype:"string"`
+	Bio                   patchField[string] `json:"bio" swaggertype:"string"`
+	Location              patchField[string] `json:"location" swaggertype:"string"`
+	// Website links, replaced as a whole
+	Links patchField[[]string] `json:"links" swaggertype:"array,string"`
+}
+
+type MarkNotificationsReadRequestBody struct {
+	// IDs of notifications to mark as read. All notifications are marked when empty
+	IDs []primitive.ObjectID `json:"ids" swaggertype:"array,string"`
 }
 
 type CreatePostRequestBody struct {
-	Content string `json:"content"`
+	Content    string `json:"content"`
+	Visibility string `json:"visibility" enums:"public,followers,mentioned,private" default:"public"`
+	// IDs of uploaded images, see POST /media
+	Media []string               `json:"media"`
+	Poll  *CreatePollRequestBody `json:"poll,omitempty"`
+	// Shown instead of the content until the reader expands the post
+	ContentWarning string `json:"contentWarning"`
+	// Attached media is sensitive and hidden until the reader reveals it
+	Sensitive bool `json:"sensitive"`
+}
+
+type UpdatePostRequestBody struct {
+	Content    string `json:"content"`
+	Visibility string `json:"visibility" enums:"public,followers,mentioned,private"`
+	// Empty string removes the content warning
+	ContentWarning *string `json:"contentWarning"`
+	Sensitive      *bool   `json:"sensitive"`
 }
 
 // CreateProfileHandler godoc
@@ -45,29 +79,57 @@ func CreateProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	name, err := parseUsername(createUserProfileData.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	email := strings.TrimSpace(createUserProfileData.Email)
+	if email != "" {
+		if email, err = parseEmail(email); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	digestSchedule := createUserProfileData.DigestSchedule
+	if digestSchedule == "" {
+		digestSchedule = digestScheduleWeekly
+	}
+	if !slices.Contains(digestSchedules, digestSchedule) {
+		http.Error(w, "Invalid digest schedule", http.StatusBadRequest)
+		return
+	}
+
 	var user = &User{
-		ID:            primitive.NewObjectID(),
-		Name:          createUserProfileData.Name,
-		Avatar:        createUserProfileData.Avatar,
-		Password:      createUserProfileData.Password,
-		Posts:         []primitive.ObjectID{},
-		LikedPosts:    []primitive.ObjectID{},
-		Notifications: []primitive.ObjectID{},
+		ID:             primitive.NewObjectID(),
+		Name:           name,
+		NameKey:        usernameKey(name),
+		Password:       createUserProfileData.Password,
+		Email:          email,
+		DigestSchedule: digestSchedule,
+		LastSignInAt:   time.Now(),
+		Posts:          []primitive.ObjectID{},
+		Notifications:  []primitive.ObjectID{},
 	}
 
 	collection := mongoClient.Database(dbName).Collection(usersCollectionName)
 
-	_, err = getUserByName(collection, createUserProfileData.Name)
-	if err == nil {
+	err = checkNameAvailable(context.Background(), name, primitive.NilObjectID)
+	if errors.Is(err, errNameTaken) {
 		http.Error(w, "User already exists", http.StatusConflict)
 		return
-	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+	} else if err != nil {
 		http.Error(w, "Server error", http.StatusInternalServerError)
 		return
 	}
 
 	_, err = collection.InsertOne(context.TODO(), user)
-	if err != nil {
+	if mongo.IsDuplicateKeyError(err) {
+		http.Error(w, "User already exists", http.StatusConflict)
+		return
+	} else if err != nil {
 		http.Error(w, "Error creating createUserProfileData", http.StatusInternalServerError)
 		return
 	}
@@ -75,11 +137,11 @@ func CreateProfileHandler(w http.ResponseWriter, r *http.Request) {
 	sessionToken, _ := generateSessionToken()
 	expiresAt := time.Now().Add(60 * 60 * 10 * time.Second)
 
-	sessions[sessionToken] = session{
+	addSession(sessionToken, session{
 		username: user.Name,
 		userId:   user.ID,
 		expiry:   expiresAt,
-	}
+	})
 
 	http.SetCookie(w, &http.Cookie{
 		Name:    cookieSessionName,
@@ -122,6 +184,7 @@ func GetProfileHandler(w http.ResponseWriter, r *http.Request) {
 
 // UpdateProfileHandler godoc
 // @Summary      Update my profile
+// @Description  JSON merge patch: missing fields are left unchanged, null clears optional fields
 // @Tags         profile
 // @Accept       json
 // @Produce      json
@@ -144,29 +207,55 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updateFields := bson.M{}
-	if updateProfileData.Name != "" {
-		updateFields["name"] = updateProfileData.Name
+	update, err := profileUpdate(updateProfileData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	if updateProfileData.Avatar != "" {
-		updateFields["avatar"] = updateProfileData.Avatar
+
+	var name string
+	if updateProfileData.Name.Set {
+		name, err = parseUsername(updateProfileData.Name.Value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
 
-	if len(updateFields) == 0 {
+	if len(update) == 0 && name == "" {
 		http.Error(w, "No update fields provided", http.StatusBadRequest)
 		return
 	}
 
-	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
-	_, err = userCollection.UpdateOne(
-		context.Background(),
-		bson.M{"_id": userID},
-		bson.M{"$set": updateFields},
-	)
+	if name != "" {
+		err = renameUser(context.Background(), userID, name)
+		if errors.Is(err, errNameTaken) {
+			http.Error(w, "Name is already taken", http.StatusConflict)
+			return
+		} else if errors.Is(err, errRenameTooSoon) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		} else if errors.Is(err, errRenameConflict) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
 
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	if len(update) > 0 {
+		userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+		_, err = userCollection.UpdateOne(
+			context.Background(),
+			bson.M{"_id": userID},
+			update,
+		)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -179,7 +268,7 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Param        request   body      main.CreatePostRequestBody  true  "Create post data"
-// @Success      200  {object}  main.Post
+// @Success      200  {object}  main.PostView
 // @Router       /posts [post]
 func CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 	userContextData := r.Context().Value(userContextKey).(*UserContextData)
@@ -191,45 +280,103 @@ func CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 	userID := userContextData.ID
 
 	var createPostData CreatePostRequestBody
-	err := json.NewDecoder(r.Body).Decode(&createPostData)
+	if !decodeContentRequest(w, r, &createPostData) {
+		return
+	}
+
+	visibility, err := parseVisibility(createPostData.Visibility)
 	if err != nil {
-		http.Error(w, "Failed to decode json", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	mediaIDs, err := parseMediaIDs(createPostData.Media)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contentWarning, err := parseContentWarning(createPostData.ContentWarning)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var poll *Poll
+	if createPostData.Poll != nil {
+		poll, err = newPoll(createPostData.Poll)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	now := time.Now()
 	post := &Post{
-		ID:         primitive.NewObjectID(),
-		Author:     userID,
-		Content:    createPostData.Content,
-		LikesCount: 0,
+		ID:             primitive.NewObjectID(),
+		Kind:           postKindPost,
+		Visibility:     visibility,
+		Author:         userID,
+		Content:        createPostData.Content,
+		Media:          mediaIDs,
+		Poll:           poll,
+		ContentWarning: contentWarning,
+		Sensitive:      createPostData.Sensitive,
+		LikesCount:     0,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	// The post is checked before media point to it, uploads of a rejected post stay attachable
+	err = preparePost(context.Background(), post)
+	if errors.Is(err, errContentTooLong) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	// TODO: add transaction
-	collection := mongoClient.Database(dbName).Collection(postsCollectionName)
-	_, err = collection.InsertOne(context.Background(), post)
+	err = attachMedia(context.Background(), userID, post.ID, mediaIDs)
+	if errors.Is(err, errMediaNotAttachable) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
+	err = insertPost(context.Background(), post)
 	if err != nil {
+		if _, findErr := findPostByID(context.Background(), post.ID); errors.Is(findErr, errPostNotFound) {
+			if err := detachMedia(context.Background(), post.ID, mediaIDs); err != nil {
+				log.Printf(">>> Failed to detach media of post %s: %v\n", post.ID.Hex(), err)
+			}
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
-	_, err = userCollection.UpdateOne(context.Background(), bson.M{"_id": userID}, bson.M{"$addToSet": bson.M{"posts": post.ID}})
+	viewer, err := loadViewer(context.Background(), userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(post)
+	json.NewEncoder(w).Encode(buildPostView(context.Background(), viewer, post))
 }
 
 // GetMyPostsHandler godoc
 // @Summary      Get my posts
+// @Description  The first page starts with pinned posts
 // @Tags         posts
 // @Accept       json
 // @Produce      json
-// @Success      200  {array}  main.Post
+// @Param        limit   query      int  false  "Max number of items to return"
+// @Param        before   query      string  false  "Cursor of the page with older items"
+// @Param        after   query      string  false  "Cursor of the page with newer items"
+// @Success      200  {object}  main.Page[main.PostView]
 // @Router       /posts [get]
 func GetMyPostsHandler(w http.ResponseWriter, r *http.Request) {
 	userContextData := r.Context().Value(userContextKey).(*UserContextData)
@@ -240,38 +387,38 @@ func GetMyPostsHandler(w http.ResponseWriter, r *http.Request) {
 
 	userID := userContextData.ID
 
-	var posts []Post
-	collection := mongoClient.Database(dbName).Collection(postsCollectionName)
-	cursor, err := collection.Find(context.Background(), bson.M{"author": userID})
+	page, err := parsePageParams(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer cursor.Close(context.Background())
-	for cursor.Next(context.Background()) {
-		var post Post
-		cursor.Decode(&post)
-		posts = append(posts, post)
-	}
-	if err := cursor.Err(); err != nil {
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if posts == nil {
-		posts = []Post{}
+	postsPage, err := userPostsPage(context.Background(), r, viewer, userID, page)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(posts)
+	json.NewEncoder(w).Encode(postsPage)
 }
 
 // GetLikedPostsHandler godoc
 // @Summary      Get posts that I've liked
+// @Description  Includes posts with any of my reactions, newest first by when I first reacted. Switching the reaction keeps the post in place
 // @Tags         posts
 // @Accept       json
 // @Produce      json
-// @Success      200  {array}  main.Post
+// @Param        limit   query      int  false  "Max number of items to return"
+// @Param        before   query      string  false  "Cursor of the page with older items"
+// @Param        after   query      string  false  "Cursor of the page with newer items"
+// @Success      200  {object}  main.Page[main.PostView]
 // @Router       /posts/liked [get]
 func GetLikedPostsHandler(w http.ResponseWriter, r *http.Request) {
 	userContextData := r.Context().Value(userContextKey).(*UserContextData)
@@ -282,53 +429,67 @@ func GetLikedPostsHandler(w http.ResponseWriter, r *http.Request) {
 
 	userID := userContextData.ID
 
-	var user User
-	collection := mongoClient.Database(dbName).Collection(usersCollectionName)
-
-	err := collection.FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user)
+	page, err := parsePageParams(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	likedPosts := user.LikedPosts
-	if likedPosts == nil {
-		likedPosts = []primitive.ObjectID{}
-	}
+	filter := page.filter("_id")
+	filter["userId"] = userID
 
-	var posts []Post
-	postCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
-	cursor, err := postCollection.Find(context.Background(), bson.M{"_id": bson.M{"$in": likedPosts}})
+	var reactions []Reaction
+	reactionCollection := mongoClient.Database(dbName).Collection(reactionsCollectionName)
+	cursor, err := reactionCollection.Find(context.Background(), filter, page.findOptions("_id"))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	defer cursor.Close(context.Background())
 	for cursor.Next(context.Background()) {
-		var post Post
-		cursor.Decode(&post)
-		posts = append(posts, post)
+		var reaction Reaction
+		cursor.Decode(&reaction)
+		reactions = append(reactions, reaction)
 	}
 	if err := cursor.Err(); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if posts == nil {
-		posts = []Post{}
+	reactionsPage := newPage(r, page, reactions, reactionKey)
+
+	postIDs := make([]primitive.ObjectID, 0, len(reactionsPage.Items))
+	for _, reaction := range reactionsPage.Items {
+		postIDs = append(postIDs, reaction.PostID)
+	}
+	postsByID := findPostsByIDs(context.Background(), postIDs)
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Posts hidden since the reaction are skipped
+	posts := make([]Post, 0, len(postIDs))
+	for _, postID := range postIDs {
+		if post, ok := postsByID[postID]; ok && viewer.canView(&post) {
+			posts = append(posts, post)
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(posts)
+	json.NewEncoder(w).Encode(buildPostViewsPage(context.Background(), viewer, Page[Post]{Items: posts, Next: reactionsPage.Next, Prev: reactionsPage.Prev}))
 }
 
 // LikePostHandler godoc
 // @Summary      Like post
+// @Description  Same as reacting with the default reaction
 // @Tags         posts
 // @Accept       json
 // @Produce      json
 // @Param     id   path      string  true  "ID of post to like"
-// @Success      200  {object}  main.Post
+// @Success      200  {object}  main.Reaction
 // @Router       /posts/{id}/like [post]
 func LikePostHandler(w http.ResponseWriter, r *http.Request) {
 	urlPath := strings.TrimPrefix(r.URL.Path, "/posts/")
@@ -349,57 +510,34 @@ func LikePostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: wrap these updates into transaction
-
-	var user User
-	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
-	err = userCollection.FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user)
-
-	if slices.Contains(user.LikedPosts, postID) {
-		http.Error(w, "Post is already liked by you", http.StatusInternalServerError)
-		return
-	}
-
+	viewer, err := loadViewer(context.Background(), userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	notification := Notification{
-		ID:      primitive.NewObjectID(),
-		Type:    "like",
-		PostID:  postID,
-		LikedBy: userID,
-	}
-
-	notificationCollection := mongoClient.Database(dbName).Collection(notificationsCollectionName)
-	_, err = notificationCollection.InsertOne(context.Background(), notification)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	post, err := findVisiblePost(context.Background(), viewer, postID)
+	if errors.Is(err, errPostNotFound) {
+		http.Error(w, "Post not found", http.StatusNotFound)
 		return
-	}
-
-	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
-	_, err = postsCollection.UpdateOne(context.Background(), bson.M{"_id": postID}, bson.M{"$inc": bson.M{"likesCount": 1}})
-	if err != nil {
+	} else if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	_, err = userCollection.UpdateOne(context.Background(), bson.M{"_id": userID}, bson.M{"$addToSet": bson.M{"likedPosts": postID}})
+	reaction, previousReaction, err := react(context.Background(), post, userID, defaultReaction)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	_, err = userCollection.UpdateOne(context.Background(), bson.M{"_id": userID}, bson.M{"$addToSet": bson.M{"notifications": notification.ID}})
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if previousReaction == defaultReaction {
+		http.Error(w, "Post is already liked by you", http.StatusConflict)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(notification)
+	json.NewEncoder(w).Encode(reaction)
 }
 
 // GetNotificationsHandler godoc
@@ -407,7 +545,10 @@ func LikePostHandler(w http.ResponseWriter, r *http.Request) {
 // @Tags         notifications
 // @Accept       json
 // @Produce      json
-// @Success      200  {array}  main.Notification
+// @Param        limit   query      int  false  "Max number of items to return"
+// @Param        before   query      string  false  "Cursor of the page with older items"
+// @Param        after   query      string  false  "Cursor of the page with newer items"
+// @Success      200  {object}  main.Page[main.Notification]
 // @Router       /notifications [get]
 func GetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 	userContextData := r.Context().Value(userContextKey).(*UserContextData)
@@ -426,9 +567,18 @@ func GetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 	//	return
 	//}
 
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := page.filter("_id")
+	filter["userId"] = userID
+
 	var notifications []Notification
 	notificationCollection := mongoClient.Database(dbName).Collection(notificationsCollectionName)
-	cursor, err := notificationCollection.Find(context.Background(), bson.M{"likedBy": userID})
+	cursor, err := notificationCollection.Find(context.Background(), filter, page.findOptions("_id"))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -444,10 +594,54 @@ func GetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if notifications == nil {
-		notifications = []Notification{}
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
+	notificationsPage := newPage(r, page, notifications, notificationKey)
+	notificationsPage.Items = visibleNotifications(context.Background(), viewer, notificationsPage.Items)
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(notifications)
+	json.NewEncoder(w).Encode(notificationsPage)
+}
+
+// MarkNotificationsReadHandler godoc
+// @Summary      Mark notifications as read
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Param        request   body      main.MarkNotificationsReadRequestBody  false  "Notifications to mark as read"
+// @Router       /notifications/read [post]
+func MarkNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	var markReadData MarkNotificationsReadRequestBody
+	err := json.NewDecoder(r.Body).Decode(&markReadData)
+	if err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	filter := bson.M{"userId": userID, "read": false}
+	if len(markReadData.IDs) > 0 {
+		filter["_id"] = bson.M{"$in": markReadData.IDs}
+	}
+
+	notificationCollection := mongoClient.Database(dbName).Collection(notificationsCollectionName)
+	_, err = notificationCollection.UpdateMany(context.Background(), filter, bson.M{"$set": bson.M{"read": true}})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Notifications marked as read"))
 }
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// Building indexes on large collections takes a while, the unique ones check every document
+const indexBuildTimeout = 5 * time.Minute
+
+type collectionIndexes struct {
+	collection string
+	models     []mongo.IndexModel
+}
+
+var indexes = []collectionIndexes{
+	{
+		collection: usersCollectionName,
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "digestSchedule", Value: 1}, {Key: "lastSignInAt", Value: 1}}},
+			{Keys: bson.D{{Key: "nameKey", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{
+				// Suspended users, hidden from every viewer
+				Keys:    bson.D{{Key: "suspendedAt", Value: 1}},
+				Options: options.Index().SetPartialFilterExpression(bson.M{"suspendedAt": bson.M{"$exists": true}}),
+			},
+		},
+	},
+	{
+		collection: blocksCollectionName,
+		models: []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "blocker", Value: 1}, {Key: "blocked", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "blocked", Value: 1}}},
+			{Keys: bson.D{{Key: "blocker", Value: 1}, {Key: "_id", Value: -1}}},
+		},
+	},
+	{
+		collection: mutesCollectionName,
+		models: []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "muter", Value: 1}, {Key: "muted", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "muter", Value: 1}, {Key: "_id", Value: -1}}},
+		},
+	},
+	{
+		collection: reservedNamesCollectionName,
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+	},
+	{
+		collection: postsCollectionName,
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "author", Value: 1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "tags", Value: 1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "mentions.userId", Value: 1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "createdAt", Value: -1}}},
+			{
+				// One repost of a post per user
+				Keys: bson.D{{Key: "originalId", Value: 1}, {Key: "author", Value: 1}},
+				Options: options.Index().
+					SetUnique(true).
+					SetPartialFilterExpression(bson.M{"kind": postKindRepost}),
+			},
+			{
+				// Posts waiting for a link preview
+				Keys:    bson.D{{Key: "previewUrl", Value: 1}},
+				Options: options.Index().SetPartialFilterExpression(bson.M{"previewUrl": bson.M{"$exists": true}}),
+			},
+			{
+				// Polls waiting for the closing job
+				Keys:    bson.D{{Key: "poll.closesAt", Value: 1}},
+				Options: options.Index().SetPartialFilterExpression(bson.M{"poll.closed": false}),
+			},
+		},
+	},
+	{
+		collection: postRevisionsCollectionName,
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "_id", Value: -1}}},
+		},
+	},
+	{
+		collection: commentsCollectionName,
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "parentId", Value: 1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "rootId", Value: 1}}},
+		},
+	},
+	{
+		collection: reactionsCollectionName,
+		models: []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "postId", Value: 1}, {Key: "userId", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "type", Value: 1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "postAuthor", Value: 1}}},
+		},
+	},
+	{
+		collection: notificationsCollectionName,
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "read", Value: 1}, {Key: "_id", Value: -1}}},
+			// Users already notified about a mention in the post
+			{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "type", Value: 1}}},
+		},
+	},
+	{
+		collection: followsCollectionName,
+		models: []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "follower", Value: 1}, {Key: "followee", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "follower", Value: 1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "followee", Value: 1}, {Key: "_id", Value: -1}}},
+		},
+	},
+	{
+		collection: timelinesCollectionName,
+		models: []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "owner", Value: 1}, {Key: "postId", Value: -1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "owner", Value: 1}, {Key: "author", Value: 1}}},
+		},
+	},
+	{
+		collection: digestsCollectionName,
+		models: []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "period", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+	},
+	{
+		collection: mediaCollectionName,
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "createdAt", Value: 1}}},
+		},
+	},
+	{
+		collection: pollVotesCollectionName,
+		models: []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "postId", Value: 1}, {Key: "userId", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "postId", Value: 1}}},
+		},
+	},
+	{
+		collection: bookmarksCollectionName,
+		models: []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "postId", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "collectionId", Value: 1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "postId", Value: 1}}},
+		},
+	},
+	{
+		collection: bookmarkCollectionsCollectionName,
+		models: []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "owner", Value: 1}, {Key: "name", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "owner", Value: 1}, {Key: "_id", Value: -1}}},
+		},
+	},
+	{
+		collection: linkPreviewsCollectionName,
+		models: []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "fetchedAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(int32(linkPreviewCacheTTL.Seconds())),
+			},
+		},
+	},
+	{
+		collection: draftsCollectionName,
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "author", Value: 1}, {Key: "_id", Value: -1}}},
+			// Due drafts are looked up by the scheduler
+			{Keys: bson.D{{Key: "publishAt", Value: 1}}},
+		},
+	},
+}
+
+// ensureIndexes creates all indexes the app relies on. Creating an index that already exists is a no-op.
+func ensureIndexes(ctx context.Context) error {
+	db := mongoClient.Database(dbName)
+	for _, idx := range indexes {
+		if _, err := db.Collection(idx.collection).Indexes().CreateMany(ctx, idx.models); err != nil {
+			return err
+		}
+	}
+	return nil
+}
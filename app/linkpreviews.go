@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/net/html"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	linkPreviewTimeout = 5 * time.Second
+	// Failed fetches are cached too, so a broken link isn't fetched for every post
+	linkPreviewCacheTTL = 24 * time.Hour
+	// A fetch which hasn't finished within this time is considered crashed and the post is picked up again
+	linkPreviewClaimLease = time.Minute
+
+	maxPreviewTitleLength       = 200
+	maxPreviewDescriptionLength = 500
+)
+
+var (
+	errBlockedAddress      = errors.New("address is not allowed")
+	errTooManyRedirects    = errors.New("too many redirects")
+	errUnsupportedPreview  = errors.New("not an HTML page")
+	errPreviewResponseSize = errors.New("response is too large")
+)
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]+`)
+
+var previewFetcher *linkPreviewFetcher
+
+// LinkPreview is the card of the first link in post content, built from OpenGraph metadata of the page.
+type LinkPreview struct {
+	URL         string `bson:"url" json:"url"`
+	Title       string `bson:"title" json:"title"`
+	Description string `bson:"description,omitempty" json:"description,omitempty"`
+	Image       string `bson:"image,omitempty" json:"image,omitempty"`
+}
+
+// cachedLinkPreview is a fetch result kept by URL for linkPreviewCacheTTL.
+type cachedLinkPreview struct {
+	URL       string       `bson:"_id"`
+	Preview   *LinkPreview `bson:"preview,omitempty"` // Not set when the page couldn't be fetched
+	FetchedAt time.Time    `bson:"fetchedAt"`
+}
+
+// firstURL returns the first http or https URL in content, or an empty string.
+func firstURL(content string) string {
+	return strings.TrimRight(urlPattern.FindString(content), ".,:;!?")
+}
+
+// linkPreviewCache keeps fetch results by URL. Failed fetches are kept as a nil preview.
+type linkPreviewCache interface {
+	// get returns found false when the URL isn't cached
+	get(ctx context.Context, pageURL string) (preview *LinkPreview, found bool, err error)
+	put(ctx context.Context, pageURL string, preview *LinkPreview) error
+}
+
+// mongoLinkPreviewCache keeps previews in the link previews collection, a TTL index expires them.
+type mongoLinkPreviewCache struct{}
+
+func (mongoLinkPreviewCache) get(ctx context.Context, pageURL string) (*LinkPreview, bool, error) {
+	var cached cachedLinkPreview
+	linkPreviewCollection := mongoClient.Database(dbName).Collection(linkPreviewsCollectionName)
+	err := linkPreviewCollection.FindOne(ctx, bson.M{"_id": pageURL}).Decode(&cached)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return cached.Preview, true, nil
+}
+
+func (mongoLinkPreviewCache) put(ctx context.Context, pageURL string, preview *LinkPreview) error {
+	linkPreviewCollection := mongoClient.Database(dbName).Collection(linkPreviewsCollectionName)
+	_, err := linkPreviewCollection.ReplaceOne(
+		ctx,
+		bson.M{"_id": pageURL},
+		cachedLinkPreview{URL: pageURL, Preview: preview, FetchedAt: time.Now()},
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// linkPreviewFetcher fetches pages for previews without letting post authors reach internal services:
+// connections go only to public addresses, checked after DNS resolution so rebinding doesn't help,
+// unless the address is in one of the allowed networks.
+type linkPreviewFetcher struct {
+	client          *http.Client
+	cache           linkPreviewCache
+	allowedNetworks []*net.IPNet
+	maxSize         int64
+}
+
+func newLinkPreviewFetcher(cache linkPreviewCache, allowedNetworks []*net.IPNet, maxSize int64, maxRedirects int) *linkPreviewFetcher {
+	fetcher := &linkPreviewFetcher{cache: cache, allowedNetworks: allowedNetworks, maxSize: maxSize}
+
+	dialer := &net.Dialer{
+		Timeout: linkPreviewTimeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !fetcher.isAllowed(ip) {
+				return errBlockedAddress
+			}
+			return nil
+		},
+	}
+
+	fetcher.client = &http.Client{
+		Timeout: linkPreviewTimeout,
+		Transport: &http.Transport{
+			// Proxies from the environment would connect on our behalf, bypassing the address check
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   linkPreviewTimeout,
+			ResponseHeaderTimeout: linkPreviewTimeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return errTooManyRedirects
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return errBlockedAddress
+			}
+			return nil
+		},
+	}
+
+	return fetcher
+}
+
+// parseAllowedNetworks parses a comma separated list of CIDRs.
+func parseAllowedNetworks(networks string) ([]*net.IPNet, error) {
+	var result []*net.IPNet
+	for _, network := range strings.Split(networks, ",") {
+		network = strings.TrimSpace(network)
+		if network == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", network, err)
+		}
+		result = append(result, ipNet)
+	}
+	return result, nil
+}
+
+// Shared address space of carrier-grade NAT, not covered by net.IP.IsPrivate
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func (f *linkPreviewFetcher) isAllowed(ip net.IP) bool {
+	for _, network := range f.allowedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip) ||
+		(ip.To4() != nil && ip.To4()[0] == 0))
+}
+
+// fetch downloads the page and extracts its preview.
+func (f *linkPreviewFetcher) fetch(ctx context.Context, pageURL string) (*LinkPreview, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("User-Agent", "social-network-link-preview/1.0")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" {
+		return nil, errUnsupportedPreview
+	}
+	if resp.ContentLength > f.maxSize {
+		return nil, errPreviewResponseSize
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > f.maxSize {
+		return nil, errPreviewResponseSize
+	}
+
+	preview := parsePreview(string(body), resp.Request.URL)
+	preview.URL = pageURL
+	if preview.Title == "" {
+		return nil, errors.New("page has no title")
+	}
+
+	return preview, nil
+}
+
+// parsePreview reads OpenGraph metadata of the page head, falling back to the title and description tags.
+func parsePreview(page string, base *url.URL) *LinkPreview {
+	var preview, fallback LinkPreview
+	tokenizer := html.NewTokenizer(strings.NewReader(page))
+	inTitle := false
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return finishPreview(preview, fallback, base)
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "body":
+				return finishPreview(preview, fallback, base)
+			case "title":
+				inTitle = true
+			case "meta":
+				var key, content string
+				for _, attr := range token.Attr {
+					switch attr.Key {
+					case "property", "name":
+						key = strings.ToLower(attr.Val)
+					case "content":
+						content = attr.Val
+					}
+				}
+				switch key {
+				case "og:title":
+					preview.Title = content
+				case "og:description":
+					preview.Description = content
+				case "og:image":
+					preview.Image = content
+				case "description":
+					fallback.Description = content
+				}
+			}
+
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			if token.Data == "title" {
+				inTitle = false
+			} else if token.Data == "head" {
+				return finishPreview(preview, fallback, base)
+			}
+
+		case html.TextToken:
+			if inTitle && fallback.Title == "" {
+				fallback.Title = string(tokenizer.Text())
+			}
+		}
+	}
+}
+
+func finishPreview(preview LinkPreview, fallback LinkPreview, base *url.URL) *LinkPreview {
+	if preview.Title == "" {
+		preview.Title = fallback.Title
+	}
+	if preview.Description == "" {
+		preview.Description = fallback.Description
+	}
+	preview.Title = truncate(strings.TrimSpace(preview.Title), maxPreviewTitleLength)
+	preview.Description = truncate(strings.TrimSpace(preview.Description), maxPreviewDescriptionLength)
+
+	// The image is loaded by clients, so only absolute web links are kept
+	if preview.Image != "" {
+		image, err := base.Parse(strings.TrimSpace(preview.Image))
+		if err == nil && (image.Scheme == "http" || image.Scheme == "https") {
+			preview.Image = image.String()
+		} else {
+			preview.Image = ""
+		}
+	}
+
+	return &preview
+}
+
+// lookup returns the preview of the URL from the cache or fetches it. Failures are cached as a nil preview.
+func (f *linkPreviewFetcher) lookup(ctx context.Context, pageURL string) (*LinkPreview, error) {
+	preview, found, err := f.cache.get(ctx, pageURL)
+	if err != nil || found {
+		return preview, err
+	}
+
+	preview, err = f.fetch(ctx, pageURL)
+	if err != nil {
+		log.Printf(">>> Failed to fetch link preview of %s: %v\n", pageURL, err)
+	}
+
+	if err := f.cache.put(ctx, pageURL, preview); err != nil {
+		return nil, err
+	}
+
+	return preview, nil
+}
+
+// attachLinkPreviews builds previews of posts waiting for one. Posts are claimed one by one,
+// so replicas running the job share the work.
+func attachLinkPreviews(ctx context.Context) error {
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	for {
+		now := time.Now()
+		var post Post
+		err := postsCollection.FindOneAndUpdate(
+			ctx,
+			bson.M{
+				"previewUrl": bson.M{"$exists": true},
+				"$or": bson.A{
+					bson.M{"previewClaimedUntil": nil},
+					bson.M{"previewClaimedUntil": bson.M{"$lt": now}},
+				},
+			},
+			bson.M{"$set": bson.M{"previewClaimedUntil": now.Add(linkPreviewClaimLease)}},
+			options.FindOneAndUpdate().SetProjection(bson.M{"previewUrl": 1}),
+		).Decode(&post)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		preview, err := previewFetcher.lookup(ctx, post.PreviewURL)
+		if err != nil {
+			log.Printf(">>> Failed to build link preview of post %s: %v\n", post.ID.Hex(), err)
+			continue
+		}
+
+		update := bson.M{"$unset": bson.M{"previewUrl": "", "previewClaimedUntil": ""}}
+		if preview != nil {
+			update["$set"] = bson.M{"preview": preview}
+		}
+
+		// Content may have changed to another link meanwhile, then the post waits for that one
+		_, err = postsCollection.UpdateOne(ctx, bson.M{"_id": post.ID, "previewUrl": post.PreviewURL}, update)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// memoryLinkPreviewCache is a linkPreviewCache kept in memory.
+type memoryLinkPreviewCache map[string]*LinkPreview
+
+func (c memoryLinkPreviewCache) get(_ context.Context, pageURL string) (*LinkPreview, bool, error) {
+	preview, found := c[pageURL]
+	return preview, found, nil
+}
+
+func (c memoryLinkPreviewCache) put(_ context.Context, pageURL string, preview *LinkPreview) error {
+	c[pageURL] = preview
+	return nil
+}
+
+const testPreviewPage = `<html><head>
+<title>Fallback title</title>
+<meta name="description" content="Fallback description">
+<meta property="og:title" content="OpenGraph title">
+<meta property="og:description" content="OpenGraph description">
+<meta property="og:image" content="/cover.png">
+</head><body><p>Content</p></body></html>`
+
+// newPreviewServer serves a preview page at /page, a body of the given size at /large
+// and a redirect to itself at /loop.
+func newPreviewServer(t *testing.T, largeSize int) (*httptest.Server, *atomic.Int32) {
+	var pageRequests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		pageRequests.Add(1)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, testPreviewPage)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<title>Large</title>"+strings.Repeat("a", largeSize))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &pageRequests
+}
+
+func newLoopbackFetcher(t *testing.T, cache linkPreviewCache, maxSize int64, maxRedirects int) *linkPreviewFetcher {
+	allowed, err := parseAllowedNetworks("127.0.0.0/8, ::1/128")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newLinkPreviewFetcher(cache, allowed, maxSize, maxRedirects)
+}
+
+func TestFetchRefusesLoopbackUnlessAllowed(t *testing.T) {
+	server, _ := newPreviewServer(t, 0)
+
+	fetcher := newLinkPreviewFetcher(memoryLinkPreviewCache{}, nil, 1<<20, 3)
+	_, err := fetcher.fetch(context.Background(), server.URL+"/page")
+	if !errors.Is(err, errBlockedAddress) {
+		t.Fatalf("fetch without allowlist: got %v, want %v", err, errBlockedAddress)
+	}
+
+	fetcher = newLoopbackFetcher(t, memoryLinkPreviewCache{}, 1<<20, 3)
+	preview, err := fetcher.fetch(context.Background(), server.URL+"/page")
+	if err != nil {
+		t.Fatalf("fetch with allowlist: %v", err)
+	}
+	if preview.Title != "OpenGraph title" {
+		t.Errorf("title: got %q", preview.Title)
+	}
+}
+
+func TestParseAllowedNetworks(t *testing.T) {
+	networks, err := parseAllowedNetworks(" 10.0.0.0/8 ,, 127.0.0.0/8")
+	if err != nil || len(networks) != 2 {
+		t.Fatalf("got %v, %v", networks, err)
+	}
+
+	if _, err := parseAllowedNetworks("localhost"); err == nil {
+		t.Error("invalid network accepted")
+	}
+}
+
+func TestFetchRedirectLimit(t *testing.T) {
+	server, _ := newPreviewServer(t, 0)
+	fetcher := newLoopbackFetcher(t, memoryLinkPreviewCache{}, 1<<20, 2)
+
+	_, err := fetcher.fetch(context.Background(), server.URL+"/loop")
+	if !errors.Is(err, errTooManyRedirects) {
+		t.Fatalf("got %v, want %v", err, errTooManyRedirects)
+	}
+}
+
+func TestFetchSizeLimit(t *testing.T) {
+	server, _ := newPreviewServer(t, 2000)
+
+	fetcher := newLoopbackFetcher(t, memoryLinkPreviewCache{}, 1000, 3)
+	_, err := fetcher.fetch(context.Background(), server.URL+"/large")
+	if !errors.Is(err, errPreviewResponseSize) {
+		t.Fatalf("got %v, want %v", err, errPreviewResponseSize)
+	}
+
+	fetcher = newLoopbackFetcher(t, memoryLinkPreviewCache{}, 4000, 3)
+	if _, err := fetcher.fetch(context.Background(), server.URL+"/large"); err != nil {
+		t.Fatalf("body within the limit: %v", err)
+	}
+}
+
+func TestParsePreview(t *testing.T) {
+	base, _ := url.Parse("https://example.com/articles/1")
+
+	preview := parsePreview(testPreviewPage, base)
+	want := LinkPreview{Title: "OpenGraph title", Description: "OpenGraph description", Image: "https://example.com/cover.png"}
+	if *preview != want {
+		t.Errorf("og tags: got %+v, want %+v", *preview, want)
+	}
+
+	fallback := `<head><title> Plain title </title><meta name="description" content="Plain description"></head>`
+	preview = parsePreview(fallback, base)
+	want = LinkPreview{Title: "Plain title", Description: "Plain description"}
+	if *preview != want {
+		t.Errorf("fallbacks: got %+v, want %+v", *preview, want)
+	}
+
+	unsafeImage := `<head><meta property="og:title" content="T"><meta property="og:image" content="javascript:alert(1)"></head>`
+	if preview = parsePreview(unsafeImage, base); preview.Image != "" {
+		t.Errorf("unsafe image kept: %q", preview.Image)
+	}
+}
+
+func TestLookupUsesCache(t *testing.T) {
+	server, pageRequests := newPreviewServer(t, 0)
+	cache := memoryLinkPreviewCache{}
+	fetcher := newLoopbackFetcher(t, cache, 1<<20, 3)
+	pageURL := server.URL + "/page"
+
+	for i := 0; i < 2; i++ {
+		preview, err := fetcher.lookup(context.Background(), pageURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if preview == nil || preview.URL != pageURL {
+			t.Fatalf("lookup %d: got %+v", i, preview)
+		}
+	}
+	if n := pageRequests.Load(); n != 1 {
+		t.Errorf("page fetched %d times, want 1", n)
+	}
+
+	// Failures are cached too
+	failedURL := server.URL + "/missing"
+	for i := 0; i < 2; i++ {
+		preview, err := fetcher.lookup(context.Background(), failedURL)
+		if err != nil || preview != nil {
+			t.Fatalf("lookup of failing page: got %+v, %v", preview, err)
+		}
+	}
+	if _, found := cache[failedURL]; !found {
+		t.Error("failure wasn't cached")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	mailerKindFile = "file"
+)
+
+type Mail struct {
+	From    string
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers outgoing emails. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, mail Mail) error
+}
+
+func newMailer(kind string, sinkDir string) (Mailer, error) {
+	switch kind {
+	case mailerKindFile, "":
+		return newFileMailer(sinkDir)
+	default:
+		return nil, fmt.Errorf("unknown mailer kind: %s", kind)
+	}
+}
+
+// fileMailer writes every email as a separate .eml file to a local directory.
+// Useful for local development where no SMTP server is available.
+type fileMailer struct {
+	dir string
+}
+
+func newFileMailer(dir string) (*fileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &fileMailer{dir: dir}, nil
+}
+
+func (m *fileMailer) Send(ctx context.Context, mail Mail) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	suffix, err := generateRandomString(4)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	fileName := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405"), suffix)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\r\n", mail.From)
+	fmt.Fprintf(&sb, "To: %s\r\n", mail.To)
+	fmt.Fprintf(&sb, "Subject: %s\r\n", mail.Subject)
+	fmt.Fprintf(&sb, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	sb.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	sb.WriteString(mail.Body)
+
+	return os.WriteFile(filepath.Join(m.dir, fileName), []byte(sb.String()), 0o644)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/caarlos0/env/v11"
 	httpSwagger "github.com/swaggo/http-swagger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"log"
@@ -24,6 +25,54 @@ type config struct {
 	MongoInitDBRootPassword string `env:"MONGO_INITDB_ROOT_PASSWORD"`
 	MongoPort               int    `env:"MONGO_PORT"`
 	MongoHost               string `env:"MONGO_HOST"`
+
+	// Digest
+	DigestInterval time.Duration `env:"DIGEST_INTERVAL" envDefault:"15m"`
+	MailerKind     string        `env:"MAILER" envDefault:"file"`
+	MailerSinkDir  string        `env:"MAILER_SINK_DIR" envDefault:"./mail"`
+	MailFrom       string        `env:"MAIL_FROM" envDefault:"digest@social-network.local"`
+
+	// Feed
+	FeedFanOut     string `env:"FEED_FANOUT" envDefault:"read"`
+	FeedRankers    string `env:"FEED_RANKERS" envDefault:"linear"`
+	RankingLogPath string `env:"RANKING_LOG_PATH" envDefault:"./ranking-features.jsonl"`
+
+	// Tags
+	TrendingInterval time.Duration `env:"TRENDING_INTERVAL" envDefault:"5m"`
+
+	// Reactions
+	Reactions string `env:"REACTIONS" envDefault:"like,love,haha,wow,sad,angry"`
+
+	// Media
+	MediaStore     string        `env:"MEDIA_STORE" envDefault:"local"`
+	MediaDir       string        `env:"MEDIA_DIR" envDefault:"./media"`
+	MediaMaxSize   int64         `env:"MEDIA_MAX_SIZE" envDefault:"10485760"`
+	MediaURLSecret string        `env:"MEDIA_URL_SECRET"`
+	MediaURLTTL    time.Duration `env:"MEDIA_URL_TTL" envDefault:"1h"`
+	S3Endpoint     string        `env:"S3_ENDPOINT"`
+	S3Region       string        `env:"S3_REGION" envDefault:"us-east-1"`
+	S3Bucket       string        `env:"S3_BUCKET"`
+	S3AccessKey    string        `env:"S3_ACCESS_KEY"`
+	S3SecretKey    string        `env:"S3_SECRET_KEY"`
+
+	// Drafts
+	SchedulerInterval time.Duration `env:"SCHEDULER_INTERVAL" envDefault:"30s"`
+
+	// Polls
+	PollMaxOptions int `env:"POLL_MAX_OPTIONS" envDefault:"4"`
+
+	// Pins
+	MaxPinnedPosts int `env:"MAX_PINNED_POSTS" envDefault:"3"`
+
+	// Link previews
+	LinkPreviewInterval        time.Duration `env:"LINK_PREVIEW_INTERVAL" envDefault:"10s"`
+	LinkPreviewMaxSize         int64         `env:"LINK_PREVIEW_MAX_SIZE" envDefault:"1048576"`
+	LinkPreviewMaxRedirects    int           `env:"LINK_PREVIEW_MAX_REDIRECTS" envDefault:"3"`
+	LinkPreviewAllowedNetworks string        `env:"LINK_PREVIEW_ALLOWED_NETWORKS"`
+
+	// User names
+	UsernameChangeInterval time.Duration `env:"USERNAME_CHANGE_INTERVAL" envDefault:"720h"`
+	UsernameReservation    time.Duration `env:"USERNAME_RESERVATION" envDefault:"2160h"`
 }
 
 var mongoClient *mongo.Client
@@ -34,10 +83,27 @@ var (
 )
 
 const (
-	dbName                      = "social-network"
-	postsCollectionName         = "posts"
-	usersCollectionName         = "users"
-	notificationsCollectionName = "notifications"
+	dbName                            = "social-network"
+	postsCollectionName               = "posts"
+	usersCollectionName               = "users"
+	notificationsCollectionName       = "notifications"
+	digestsCollectionName             = "digests"
+	migrationsCollectionName          = "migrations"
+	followsCollectionName             = "follows"
+	timelinesCollectionName           = "timelines"
+	postRevisionsCollectionName       = "post_revisions"
+	commentsCollectionName            = "comments"
+	reactionsCollectionName           = "reactions"
+	trendingTagsCollectionName        = "trending_tags"
+	mediaCollectionName               = "media"
+	draftsCollectionName              = "drafts"
+	pollVotesCollectionName           = "poll_votes"
+	bookmarksCollectionName           = "bookmarks"
+	bookmarkCollectionsCollectionName = "bookmark_collections"
+	linkPreviewsCollectionName        = "link_previews"
+	reservedNamesCollectionName       = "reserved_names"
+	blocksCollectionName              = "blocks"
+	mutesCollectionName               = "mutes"
 )
 
 // @title API of social-network test project
@@ -53,22 +119,105 @@ func main() {
 	port = cfg.Port
 	mongoURL = fmt.Sprintf("mongodb://%s:%s@%s:%d", cfg.MongoInitDBRootUsername, "***", cfg.MongoHost, cfg.MongoPort)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// The timeout covers connecting only, migrations may wait for other replicas much longer
+	connectCtx, cancelConnect := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelConnect()
 
 	log.Printf(">>> Connecting ro Mongo: %s ...\n", mongoURL)
 
 	clientOptions := options.Client().ApplyURI(mongoURL)
 
 	var err error
-	mongoClient, err = mongo.Connect(ctx, clientOptions)
-
+	mongoClient, err = mongo.Connect(connectCtx, clientOptions)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := mongoClient.Ping(connectCtx, nil); err != nil {
+		log.Fatal(err)
+	}
 
 	log.Println(">>> Connecting to mongodb: DONE")
 
+	// Migrations run first, so unique indexes are built on migrated data
+	if err := runMigrations(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	indexesCtx, cancelIndexes := context.WithTimeout(context.Background(), indexBuildTimeout)
+	defer cancelIndexes()
+	if err := ensureIndexes(indexesCtx); err != nil {
+		log.Fatal(err)
+	}
+
+	mailer, err = newMailer(cfg.MailerKind, cfg.MailerSinkDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mailFrom = cfg.MailFrom
+
+	feedStrategy, err = newFeedStrategy(cfg.FeedFanOut)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rankers, err = newRankers(cfg.FeedRankers)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rankingLogger, err = newFeatureLogger(cfg.RankingLogPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	reactionTypes, err = parseReactionTypes(cfg.Reactions)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	blobStore, err = newBlobStore(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mediaMaxSize = cfg.MediaMaxSize
+	mediaURLTTL = cfg.MediaURLTTL
+	mediaURLSecret = []byte(cfg.MediaURLSecret)
+	if len(mediaURLSecret) == 0 {
+		// Signed links stop working after a restart then
+		log.Println(">>> MEDIA_URL_SECRET is not set, using a random one")
+		secret, err := generateRandomString(32)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mediaURLSecret = []byte(secret)
+	}
+
+	maxPollOptions = cfg.PollMaxOptions
+	if maxPollOptions < minPollOptions {
+		log.Fatalf("POLL_MAX_OPTIONS must be at least %d", minPollOptions)
+	}
+
+	maxPinnedPosts = cfg.MaxPinnedPosts
+	if maxPinnedPosts < 1 {
+		log.Fatal("MAX_PINNED_POSTS must be at least 1")
+	}
+
+	usernameChangeInterval = cfg.UsernameChangeInterval
+	usernameReservation = cfg.UsernameReservation
+
+	allowedNetworks, err := parseAllowedNetworks(cfg.LinkPreviewAllowedNetworks)
+	if err != nil {
+		log.Fatal(err)
+	}
+	previewFetcher = newLinkPreviewFetcher(mongoLinkPreviewCache{}, allowedNetworks, cfg.LinkPreviewMaxSize, cfg.LinkPreviewMaxRedirects)
+
+	go runPeriodically(context.Background(), "digest", cfg.DigestInterval, sendDigests)
+	go runPeriodically(context.Background(), "trending-tags", cfg.TrendingInterval, refreshTrendingTags)
+	go runPeriodically(context.Background(), "orphan-media", time.Hour, deleteOrphanMedia)
+	go runPeriodically(context.Background(), "scheduled-posts", cfg.SchedulerInterval, publishScheduledPosts)
+	go runPeriodically(context.Background(), "closed-polls", time.Minute, closePolls)
+	go runPeriodically(context.Background(), "link-previews", cfg.LinkPreviewInterval, attachLinkPreviews)
+
 	http.HandleFunc("/swagger/*", methodHandler(http.MethodGet, httpSwagger.Handler(
 		httpSwagger.URL(fmt.Sprintf("http://localhost:%d/swagger/doc.json", port)), //The url pointing to API definition
 	)))
@@ -88,6 +237,16 @@ func main() {
 		}
 	})
 
+	http.HandleFunc("/profile/avatar", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			authMiddleware(methodHandler(http.MethodPut, UploadAvatarHandler))(w, r)
+		} else {
+			authMiddleware(methodHandler(http.MethodDelete, DeleteAvatarHandler))(w, r)
+		}
+	})
+	// Match /avatars/:id/:size.jpg, avatars are public
+	http.HandleFunc("/avatars/", methodHandler(http.MethodGet, GetAvatarHandler))
+
 	http.HandleFunc("/posts", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			authMiddleware(methodHandler(http.MethodPost, CreatePostHandler))(w, r)
@@ -99,24 +258,195 @@ func main() {
 	})
 
 	http.HandleFunc("/posts/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		// Match /posts/:id/like
+		case strings.HasSuffix(r.URL.Path, "/like"):
+			authMiddleware(methodHandler(http.MethodPost, LikePostHandler))(w, r)
+		// Match /posts/:id/reaction
+		case strings.HasSuffix(r.URL.Path, "/reaction") && r.Method == http.MethodPut:
+			authMiddleware(methodHandler(http.MethodPut, ReactToPostHandler))(w, r)
+		case strings.HasSuffix(r.URL.Path, "/reaction"):
+			authMiddleware(methodHandler(http.MethodDelete, RemoveReactionHandler))(w, r)
+		// Match /posts/:id/reactions
+		case strings.HasSuffix(r.URL.Path, "/reactions"):
+			authMiddleware(methodHandler(http.MethodGet, GetReactionsHandler))(w, r)
+		// Match /posts/:id/comments
+		case strings.HasSuffix(r.URL.Path, "/comments") && r.Method == http.MethodPost:
+			authMiddleware(methodHandler(http.MethodPost, CreateCommentHandler))(w, r)
+		case strings.HasSuffix(r.URL.Path, "/comments"):
+			authMiddleware(methodHandler(http.MethodGet, GetCommentsHandler))(w, r)
+		// Match /posts/:id/repost
+		case strings.HasSuffix(r.URL.Path, "/repost") && r.Method == http.MethodPost:
+			authMiddleware(methodHandler(http.MethodPost, RepostHandler))(w, r)
+		case strings.HasSuffix(r.URL.Path, "/repost"):
+			authMiddleware(methodHandler(http.MethodDelete, UndoRepostHandler))(w, r)
+		// Match /posts/:id/quote
+		case strings.HasSuffix(r.URL.Path, "/quote"):
+			authMiddleware(methodHandler(http.MethodPost, QuotePostHandler))(w, r)
+		// Match /posts/:id/bookmark
+		case strings.HasSuffix(r.URL.Path, "/bookmark") && r.Method == http.MethodPut:
+			authMiddleware(methodHandler(http.MethodPut, BookmarkPostHandler))(w, r)
+		case strings.HasSuffix(r.URL.Path, "/bookmark"):
+			authMiddleware(methodHandler(http.MethodDelete, RemoveBookmarkHandler))(w, r)
+		// Match /posts/:id/pin
+		case strings.HasSuffix(r.URL.Path, "/pin") && r.Method == http.MethodPut:
+			authMiddleware(methodHandler(http.MethodPut, PinPostHandler))(w, r)
+		case strings.HasSuffix(r.URL.Path, "/pin"):
+			authMiddleware(methodHandler(http.MethodDelete, UnpinPostHandler))(w, r)
+		// Match /posts/:id/sensitive
+		case strings.HasSuffix(r.URL.Path, "/sensitive"):
+			authMiddleware(methodHandler(http.MethodPut, SetSensitiveHandler))(w, r)
+		// Match /posts/:id/vote
+		case strings.HasSuffix(r.URL.Path, "/vote"):
+			authMiddleware(methodHandler(http.MethodPut, VoteHandler))(w, r)
+		// Match /posts/:id/revisions
+		case strings.HasSuffix(r.URL.Path, "/revisions"):
+			authMiddleware(methodHandler(http.MethodGet, GetPostRevisionsHandler))(w, r)
+		// Match /posts/:id
+		case r.Method == http.MethodGet:
+			authMiddleware(methodHandler(http.MethodGet, GetPostHandler))(w, r)
+		case r.Method == http.MethodPatch:
+			authMiddleware(methodHandler(http.MethodPatch, UpdatePostHandler))(w, r)
+		case r.Method == http.MethodDelete:
+			authMiddleware(methodHandler(http.MethodDelete, DeletePostHandler))(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/users/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		// Match /users/id/:id
+		case strings.HasPrefix(r.URL.Path, "/users/id/"):
+			authMiddleware(methodHandler(http.MethodGet, GetUserProfileByIDHandler))(w, r)
+		// Match /users/:name, checked before suffixes as names may look like them
+		case !strings.Contains(strings.TrimPrefix(r.URL.Path, "/users/"), "/"):
+			authMiddleware(methodHandler(http.MethodGet, GetUserProfileHandler))(w, r)
+		// Match /users/:id/follow
+		case strings.HasSuffix(r.URL.Path, "/follow") && r.Method == http.MethodPost:
+			authMiddleware(methodHandler(http.MethodPost, FollowUserHandler))(w, r)
+		case strings.HasSuffix(r.URL.Path, "/follow") && r.Method == http.MethodDelete:
+			authMiddleware(methodHandler(http.MethodDelete, UnfollowUserHandler))(w, r)
+		// Match /users/:id/followers
+		case strings.HasSuffix(r.URL.Path, "/followers"):
+			authMiddleware(methodHandler(http.MethodGet, GetFollowersHandler))(w, r)
+		// Match /users/:id/following
+		case strings.HasSuffix(r.URL.Path, "/following"):
+			authMiddleware(methodHandler(http.MethodGet, GetFollowingHandler))(w, r)
+		// Match /users/:id/posts
+		case strings.HasSuffix(r.URL.Path, "/posts"):
+			authMiddleware(methodHandler(http.MethodGet, GetUserPostsHandler))(w, r)
+		// Match /users/:id/block
+		case strings.HasSuffix(r.URL.Path, "/block") && r.Method == http.MethodPut:
+			authMiddleware(methodHandler(http.MethodPut, BlockUserHandler))(w, r)
+		case strings.HasSuffix(r.URL.Path, "/block"):
+			authMiddleware(methodHandler(http.MethodDelete, UnblockUserHandler))(w, r)
+		// Match /users/:id/mute
+		case strings.HasSuffix(r.URL.Path, "/mute") && r.Method == http.MethodPut:
+			authMiddleware(methodHandler(http.MethodPut, MuteUserHandler))(w, r)
+		case strings.HasSuffix(r.URL.Path, "/mute"):
+			authMiddleware(methodHandler(http.MethodDelete, UnmuteUserHandler))(w, r)
+		// Match /users/:id/suspension
+		case strings.HasSuffix(r.URL.Path, "/suspension") && r.Method == http.MethodPut:
+			authMiddleware(methodHandler(http.MethodPut, SuspendUserHandler))(w, r)
+		case strings.HasSuffix(r.URL.Path, "/suspension"):
+			authMiddleware(methodHandler(http.MethodDelete, ReinstateUserHandler))(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/comments/", func(w http.ResponseWriter, r *http.Request) {
+		// Match /comments/:id
+		if r.Method == http.MethodPatch {
+			authMiddleware(methodHandler(http.MethodPatch, UpdateCommentHandler))(w, r)
+		} else if r.Method == http.MethodDelete {
+			authMiddleware(methodHandler(http.MethodDelete, DeleteCommentHandler))(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/media", authMiddleware(methodHandler(http.MethodPost, UploadMediaHandler)))
+	http.HandleFunc("/media/", func(w http.ResponseWriter, r *http.Request) {
+		// Media links are signed, no session needed
+		switch {
+		// Match /media/:id/thumbnail
+		case strings.HasSuffix(r.URL.Path, "/thumbnail"):
+			methodHandler(http.MethodGet, GetMediaThumbnailHandler)(w, r)
+		// Match /media/:id
+		default:
+			methodHandler(http.MethodGet, GetMediaHandler)(w, r)
+		}
+	})
+
+	http.HandleFunc("/drafts", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			// Match /posts/:id/like
-			if strings.HasSuffix(r.URL.Path, "/like") {
-				authMiddleware(methodHandler(http.MethodPost, LikePostHandler))(w, r)
-				return
-			}
+			authMiddleware(methodHandler(http.MethodPost, CreateDraftHandler))(w, r)
+		} else if r.Method == http.MethodGet {
+			authMiddleware(methodHandler(http.MethodGet, GetDraftsHandler))(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/drafts/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		// Match /drafts/:id/publish
+		case strings.HasSuffix(r.URL.Path, "/publish"):
+			authMiddleware(methodHandler(http.MethodPost, PublishDraftHandler))(w, r)
+		// Match /drafts/:id
+		case r.Method == http.MethodPatch:
+			authMiddleware(methodHandler(http.MethodPatch, UpdateDraftHandler))(w, r)
+		case r.Method == http.MethodDelete:
+			authMiddleware(methodHandler(http.MethodDelete, DeleteDraftHandler))(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	})
 
+	http.HandleFunc("/bookmarks", authMiddleware(methodHandler(http.MethodGet, GetBookmarksHandler)))
+	http.HandleFunc("/bookmarks/collections", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			authMiddleware(methodHandler(http.MethodPost, CreateBookmarkCollectionHandler))(w, r)
+		} else {
+			authMiddleware(methodHandler(http.MethodGet, GetBookmarkCollectionsHandler))(w, r)
+		}
+	})
+	http.HandleFunc("/bookmarks/collections/", func(w http.ResponseWriter, r *http.Request) {
+		// Match /bookmarks/collections/:id
+		if r.Method == http.MethodPatch {
+			authMiddleware(methodHandler(http.MethodPatch, UpdateBookmarkCollectionHandler))(w, r)
+		} else {
+			authMiddleware(methodHandler(http.MethodDelete, DeleteBookmarkCollectionHandler))(w, r)
+		}
+	})
+
+	http.HandleFunc("/tags/trending", authMiddleware(methodHandler(http.MethodGet, GetTrendingTagsHandler)))
+	// Match /tags/:tag/posts
+	http.HandleFunc("/tags/", authMiddleware(methodHandler(http.MethodGet, GetTagPostsHandler)))
+
 	http.HandleFunc("/posts/liked", authMiddleware(methodHandler(http.MethodGet, GetLikedPostsHandler)))
+	http.HandleFunc("/mentions", authMiddleware(methodHandler(http.MethodGet, GetMentionsHandler)))
+	http.HandleFunc("/feed", authMiddleware(methodHandler(http.MethodGet, GetFeedHandler)))
+	http.HandleFunc("/blocks", authMiddleware(methodHandler(http.MethodGet, GetBlockedUsersHandler)))
+	http.HandleFunc("/mutes", authMiddleware(methodHandler(http.MethodGet, GetMutedUsersHandler)))
+
 	http.HandleFunc("/notifications", authMiddleware(methodHandler(http.MethodGet, GetNotificationsHandler)))
+	http.HandleFunc("/notifications/read", authMiddleware(methodHandler(http.MethodPost, MarkNotificationsReadHandler)))
 
 	log.Printf(">>> Starting server on port %d...\n", port)
 	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), nil))
 
 }
 
+// pathObjectID extracts an ObjectID placed between prefix and suffix of the URL path, e.g. /users/:id/follow.
+func pathObjectID(path string, prefix string, suffix string) (primitive.ObjectID, error) {
+	id := strings.TrimPrefix(path, prefix)
+	id = strings.TrimSuffix(id, suffix)
+	return primitive.ObjectIDFromHex(id)
+}
+
 func methodHandler(method string, handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != method {
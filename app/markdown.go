@@ -0,0 +1,264 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// The source is limited before it's rendered, and the rendered content too, as few characters can expand a lot
+	maxContentLength     = 20000
+	maxContentHTMLLength = 20000
+	// Requests carrying content are limited before decoding, this leaves room for escaping every character in JSON
+	maxContentRequestSize = 8 * maxContentLength
+)
+
+var errContentTooLong = fmt.Errorf("content must be at most %d characters long, also once rendered", maxContentLength)
+
+var (
+	unorderedItemPattern = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedItemPattern   = regexp.MustCompile(`^\s*\d{1,9}[.)]\s+(.*)$`)
+)
+
+var linkSchemes = []string{"http", "https", "mailto"}
+
+// renderContent renders post content written in a Markdown subset to HTML: paragraphs, line breaks, lists, code blocks,
+// inline code, emphasis and links, plus links for resolved mentions and hashtags. Raw HTML isn't supported, tags are
+// produced only by the renderer and all text is escaped, so the result is safe to embed as is.
+func renderContent(content string, mentions []Mention) string {
+	renderer := contentRenderer{mentions: make(map[string]string, len(mentions))}
+	for _, mention := range mentions {
+		renderer.mentions[mention.Name] = mention.UserID.Hex()
+	}
+
+	var b strings.Builder
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case strings.HasPrefix(strings.TrimSpace(line), "```"):
+			i++
+			var code []string
+			for ; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			i++ // Closing fence
+			b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>")
+
+		case unorderedItemPattern.MatchString(line):
+			i = renderer.list(&b, lines, i, "ul", unorderedItemPattern)
+
+		case orderedItemPattern.MatchString(line):
+			i = renderer.list(&b, lines, i, "ol", orderedItemPattern)
+
+		default:
+			var paragraph []string
+			for ; i < len(lines) && isParagraphLine(lines[i]); i++ {
+				paragraph = append(paragraph, renderer.inline(strings.TrimSpace(lines[i]), false))
+			}
+			b.WriteString("<p>" + strings.Join(paragraph, "<br>") + "</p>")
+		}
+	}
+
+	return b.String()
+}
+
+// checkContentSource rejects content which is too long to be rendered at all.
+func checkContentSource(content string) error {
+	if len(content) > maxContentLength {
+		return errContentTooLong
+	}
+	return nil
+}
+
+// checkContentLength rejects content whose rendering exceeds the limit.
+func checkContentLength(contentHTML string) error {
+	if len(contentHTML) > maxContentHTMLLength {
+		return errContentTooLong
+	}
+	return nil
+}
+
+func isParagraphLine(line string) bool {
+	return strings.TrimSpace(line) != "" &&
+		!strings.HasPrefix(strings.TrimSpace(line), "```") &&
+		!unorderedItemPattern.MatchString(line) &&
+		!orderedItemPattern.MatchString(line)
+}
+
+type contentRenderer struct {
+	// Hex user IDs by mentioned name
+	mentions map[string]string
+}
+
+// list renders consecutive items matching the pattern as a list and returns the index of the first line after it.
+func (c contentRenderer) list(b *strings.Builder, lines []string, i int, tag string, pattern *regexp.Regexp) int {
+	b.WriteString("<" + tag + ">")
+	for ; i < len(lines); i++ {
+		match := pattern.FindStringSubmatch(lines[i])
+		if match == nil {
+			break
+		}
+		b.WriteString("<li>" + c.inline(strings.TrimSpace(match[1]), false) + "</li>")
+	}
+	b.WriteString("</" + tag + ">")
+	return i
+}
+
+// inline renders inline markup of a single line. Links can't be nested, so inside a link text
+// neither links nor mentions and hashtags are rendered.
+func (c contentRenderer) inline(s string, inLink bool) string {
+	var b strings.Builder
+	closers := indexCache{s: s, next: map[string]int{}}
+
+	for i := 0; i < len(s); {
+		ch := s[i]
+
+		if ch == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_[]()#@", s[i+1]) >= 0 {
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+		}
+
+		if ch == '`' {
+			if end := closers.index("`", i+1); end > i+1 {
+				b.WriteString("<code>" + html.EscapeString(s[i+1:end]) + "</code>")
+				i = end + 1
+				continue
+			}
+		}
+
+		if (ch == '*' || ch == '_') && (ch == '*' || !isWordRune(lastRune(s[:i]))) {
+			delimiter := s[i : i+1]
+			tag := "em"
+			if strings.HasPrefix(s[i+1:], delimiter) {
+				delimiter += delimiter
+				tag = "strong"
+			}
+
+			start := i + len(delimiter)
+			if end := closers.index(delimiter, start); end > start && !unicode.IsSpace(firstRune(s[start:])) {
+				b.WriteString("<" + tag + ">" + c.inline(s[start:end], inLink) + "</" + tag + ">")
+				i = end + len(delimiter)
+				continue
+			}
+		}
+
+		if ch == '[' && !inLink {
+			if text, target, end, err := parseLink(s, i, &closers); err == nil {
+				b.WriteString(`<a href="` + html.EscapeString(target) + `" rel="nofollow noopener noreferrer">` + c.inline(text, true) + "</a>")
+				i = end
+				continue
+			}
+		}
+
+		if ch == '@' && !inLink && !isWordRune(lastRune(s[:i])) && lastRune(s[:i]) != '@' {
+			name := leadingWord(s[i+1:])
+			if userID, ok := c.mentions[name]; ok {
+				b.WriteString(`<a href="/users/` + userID + `/posts">@` + html.EscapeString(name) + "</a>")
+				i += 1 + len(name)
+				continue
+			}
+		}
+
+		if ch == '#' && !inLink && !isWordRune(lastRune(s[:i])) && !strings.ContainsRune("&#", lastRune(s[:i])) {
+			word := leadingWord(s[i+1:])
+			if tag := normalizeTag(word); tag != "" {
+				b.WriteString(`<a href="/tags/` + url.PathEscape(tag) + `/posts">#` + html.EscapeString(word) + "</a>")
+				i += 1 + len(word)
+				continue
+			}
+		}
+
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(html.EscapeString(s[i : i+size]))
+		i += size
+	}
+
+	return b.String()
+}
+
+// parseLink parses [text](target) starting at s[start] and returns the index right after it.
+// Only absolute http, https and mailto targets are accepted.
+func parseLink(s string, start int, closers *indexCache) (text string, target string, end int, err error) {
+	closing := closers.index("](", start)
+	if closing <= start+1 {
+		return "", "", 0, errors.New("not a link")
+	}
+	paren := closers.index(")", closing+2)
+	if paren < 0 {
+		return "", "", 0, errors.New("not a link")
+	}
+
+	text = s[start+1 : closing]
+	target = strings.TrimSpace(s[closing+2 : paren])
+	parsed, err := url.Parse(target)
+	if err != nil || strings.ContainsAny(target, " \t") || strings.ContainsRune(text, '[') {
+		return "", "", 0, errors.New("not a link")
+	}
+	if !slices.Contains(linkSchemes, parsed.Scheme) || (parsed.Scheme != "mailto" && parsed.Host == "") {
+		return "", "", 0, errors.New("unsupported link target")
+	}
+
+	return text, target, paren + 1, nil
+}
+
+// indexCache finds the next occurrence of a closing delimiter in s. A line is rendered left to right, so
+// the searches for a delimiter start further and further and each part of s is scanned at most once per delimiter.
+// Searching from scratch for every opening delimiter would make rendering quadratic.
+type indexCache struct {
+	s string
+	// Index of the next occurrence of a delimiter at or after the last search start, -1 when there's none
+	next map[string]int
+}
+
+// index returns the index of the first occurrence of delimiter in s at or after from, or -1.
+// Calls for the same delimiter must not decrease from.
+func (c *indexCache) index(delimiter string, from int) int {
+	if next, ok := c.next[delimiter]; ok && (next < 0 || next >= from) {
+		return next
+	}
+
+	next := -1
+	if from <= len(c.s) {
+		if n := strings.Index(c.s[from:], delimiter); n >= 0 {
+			next = from + n
+		}
+	}
+	c.next[delimiter] = next
+	return next
+}
+
+// leadingWord returns the run of letters, digits and underscores s starts with, as in tagPattern and mentionPattern.
+func leadingWord(s string) string {
+	end := strings.IndexFunc(s, func(r rune) bool { return !isWordRune(r) })
+	if end < 0 {
+		return s
+	}
+	return s[:end]
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_'
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
@@ -0,0 +1,512 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	maxMediaPerPost = 4
+	// Decoded images are kept in memory, larger ones are rejected before decoding
+	maxImagePixels = 40_000_000
+	thumbnailSize  = 320
+	jpegQuality    = 90
+	// Uploads not attached to a post within this time are removed
+	orphanMediaTTL = 24 * time.Hour
+
+	mediaVariantOriginal  = "original"
+	mediaVariantThumbnail = "thumbnail"
+)
+
+var (
+	errUnsupportedMedia   = errors.New("only JPEG, PNG and GIF images are supported")
+	errMediaTooLarge      = errors.New("image is too large")
+	errAnimatedImage      = errors.New("animated GIFs aren't supported")
+	errMediaNotAttachable = errors.New("media not found or already attached")
+)
+
+var (
+	mediaMaxSize   int64
+	mediaURLSecret []byte
+	mediaURLTTL    time.Duration
+)
+
+// processedImage is an uploaded image re-encoded for storing.
+type processedImage struct {
+	ContentType string
+	Data        []byte
+	Width       int
+	Height      int
+}
+
+// processImage validates an uploaded image and re-encodes it. Only pixels are re-encoded,
+// so EXIF and any other metadata of the upload are dropped, JPEGs are turned upright by their EXIF orientation first.
+// GIFs are stored as PNG, animated ones are rejected as only their first frame would be kept.
+func processImage(data []byte) (original *processedImage, thumbnail *processedImage, err error) {
+	switch http.DetectContentType(data) {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, nil, errUnsupportedMedia
+	}
+
+	imageConfig, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, errUnsupportedMedia
+	}
+	if imageConfig.Width*imageConfig.Height > maxImagePixels {
+		return nil, nil, errMediaTooLarge
+	}
+	if gifFrameCount(data) > 1 {
+		return nil, nil, errAnimatedImage
+	}
+
+	orientation := jpegOrientation(data)
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, errUnsupportedMedia
+	}
+	img = orientImage(img, orientation)
+
+	original, err = encodeImage(img, format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	thumbnail, err = encodeImage(fitImage(img, thumbnailSize), format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return original, thumbnail, nil
+}
+
+// jpegOrientation reads the orientation tag from EXIF of a JPEG, 1 (upright) when there is none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	// Segments up to the start of the scan, EXIF is an APP1 segment among them
+	for pos := 2; pos+4 <= len(data) && data[pos] == 0xFF; {
+		marker := data[pos+1]
+		if marker == 0xFF {
+			// Fill byte before a marker
+			pos++
+			continue
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if marker == 0xDA || length < 2 || pos+2+length > len(data) {
+			break
+		}
+		payload := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+			return exifOrientation(payload[6:])
+		}
+		pos += 2 + length
+	}
+
+	return 1
+}
+
+// exifOrientation finds the orientation tag in the first IFD of TIFF encoded EXIF data.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		// The orientation is a single SHORT stored in the value field itself
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+			break
+		}
+	}
+
+	return 1
+}
+
+// orientImage rotates and flips the image as EXIF orientation 2 to 8 says it's meant to be shown.
+func orientImage(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	// Orientations 5 to 8 swap the width and the height
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = width-1-x, y
+			case 3:
+				sx, sy = width-1-x, height-1-y
+			case 4:
+				sx, sy = x, height-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, height-1-x
+			case 7:
+				sx, sy = width-1-y, height-1-x
+			case 8:
+				sx, sy = width-1-y, x
+			}
+			dst.Set(x, y, img.At(bounds.Min.X+sx, bounds.Min.Y+sy))
+		}
+	}
+
+	return dst
+}
+
+// gifFrameCount counts images of a GIF by walking its blocks without decoding them, 0 for other formats.
+// Counting stops at the second frame.
+func gifFrameCount(data []byte) int {
+	if len(data) < 13 || !bytes.HasPrefix(data, []byte("GIF8")) {
+		return 0
+	}
+
+	pos := 13
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&0x07 + 1)
+	}
+
+	frames := 0
+	for pos < len(data) && frames < 2 {
+		switch data[pos] {
+		case 0x21:
+			// Extension: label, then data sub-blocks
+			pos += 2
+		case 0x2C:
+			// Image descriptor, optional local color table, LZW code size, then data sub-blocks
+			frames++
+			if pos+10 > len(data) {
+				return frames
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			pos++
+		default:
+			return frames
+		}
+
+		for pos < len(data) && data[pos] != 0 {
+			pos += int(data[pos]) + 1
+		}
+		pos++
+	}
+
+	return frames
+}
+
+// encodeImage encodes JPEGs as JPEG and everything else as PNG.
+func encodeImage(img image.Image, format string) (*processedImage, error) {
+	var buf bytes.Buffer
+	result := &processedImage{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+
+	if format == "jpeg" {
+		result.ContentType = "image/jpeg"
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+	} else {
+		result.ContentType = "image/png"
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+	}
+
+	result.Data = buf.Bytes()
+	return result, nil
+}
+
+// fitImage scales the image down to fit into a size x size square keeping the aspect ratio. Smaller images are kept as is.
+func fitImage(img image.Image, size int) image.Image {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if width <= size && height <= size {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+
+	return resizeImage(img, width, height)
+}
+
+// resizeImage scales the image down to width x height averaging all source pixels covered by a target pixel.
+func resizeImage(src image.Image, width int, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	bounds := src.Bounds()
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+
+	return dst
+}
+
+// parseMediaIDs validates IDs of media to attach to a post, duplicates are dropped.
+func parseMediaIDs(hexIDs []string) ([]primitive.ObjectID, error) {
+	if len(hexIDs) > maxMediaPerPost {
+		return nil, fmt.Errorf("up to %d images can be attached", maxMediaPerPost)
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(hexIDs))
+	for _, hexID := range hexIDs {
+		id, err := primitive.ObjectIDFromHex(hexID)
+		if err != nil {
+			return nil, errors.New("invalid media ID")
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// storeMedia processes an uploaded image and saves it with its thumbnail.
+func storeMedia(ctx context.Context, owner primitive.ObjectID, data []byte) (*Media, error) {
+	original, thumbnail, err := processImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	id := primitive.NewObjectID()
+	media := &Media{
+		ID:           id,
+		Owner:        owner,
+		ContentType:  original.ContentType,
+		Size:         len(original.Data),
+		Width:        original.Width,
+		Height:       original.Height,
+		Key:          "media/" + id.Hex(),
+		ThumbnailKey: "media/" + id.Hex() + "-thumbnail",
+		CreatedAt:    time.Now(),
+	}
+
+	if err := blobStore.Put(ctx, media.Key, original.Data, original.ContentType); err != nil {
+		return nil, err
+	}
+	if err := blobStore.Put(ctx, media.ThumbnailKey, thumbnail.Data, thumbnail.ContentType); err != nil {
+		return nil, err
+	}
+
+	mediaCollection := mongoClient.Database(dbName).Collection(mediaCollectionName)
+	_, err = mediaCollection.InsertOne(ctx, media)
+	if err != nil {
+		return nil, err
+	}
+
+	return media, nil
+}
+
+// attachMedia marks uploads of the owner as attached to the post or draft. Every upload can be attached only once,
+// so either all of them get attached or none.
+func attachMedia(ctx context.Context, owner primitive.ObjectID, postID primitive.ObjectID, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	mediaCollection := mongoClient.Database(dbName).Collection(mediaCollectionName)
+	result, err := mediaCollection.UpdateMany(
+		ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "owner": owner, "postId": nil},
+		bson.M{"$set": bson.M{"postId": postID}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.ModifiedCount != int64(len(ids)) {
+		if err := detachMedia(ctx, postID, ids); err != nil {
+			return err
+		}
+		return errMediaNotAttachable
+	}
+
+	return nil
+}
+
+// detachMedia gives uploads attached to a post which was never saved back to their owner, so they can be
+// attached again or cleaned up as orphans.
+func detachMedia(ctx context.Context, postID primitive.ObjectID, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	mediaCollection := mongoClient.Database(dbName).Collection(mediaCollectionName)
+	_, err := mediaCollection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "postId": postID}, bson.M{"$unset": bson.M{"postId": ""}})
+	return err
+}
+
+// deleteMedia removes the media documents matching filter together with their blobs.
+func deleteMedia(ctx context.Context, filter bson.M) error {
+	var mediaList []Media
+	mediaCollection := mongoClient.Database(dbName).Collection(mediaCollectionName)
+	cursor, err := mediaCollection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if err := cursor.All(ctx, &mediaList); err != nil {
+		return err
+	}
+
+	for _, media := range mediaList {
+		if err := blobStore.Delete(ctx, media.Key); err != nil {
+			return err
+		}
+		if err := blobStore.Delete(ctx, media.ThumbnailKey); err != nil {
+			return err
+		}
+		if _, err := mediaCollection.DeleteOne(ctx, bson.M{"_id": media.ID}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteOrphanMedia removes uploads which were never attached to a post.
+func deleteOrphanMedia(ctx context.Context) error {
+	return deleteMedia(ctx, bson.M{"postId": nil, "createdAt": bson.M{"$lt": time.Now().Add(-orphanMediaTTL)}})
+}
+
+// buildMediaViews loads media by ID and signs their URLs. Unknown IDs are skipped.
+func buildMediaViews(ctx context.Context, ids []primitive.ObjectID) map[primitive.ObjectID]MediaView {
+	views := map[primitive.ObjectID]MediaView{}
+	if len(ids) == 0 {
+		return views
+	}
+
+	mediaCollection := mongoClient.Database(dbName).Collection(mediaCollectionName)
+	cursor, err := mediaCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		log.Printf(">>> Failed to load media: %v\n", err)
+		return views
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var media Media
+		if err := cursor.Decode(&media); err == nil {
+			views[media.ID] = newMediaView(&media)
+		}
+	}
+
+	return views
+}
+
+func newMediaView(media *Media) MediaView {
+	expires := time.Now().Add(mediaURLTTL).Truncate(time.Minute)
+	return MediaView{
+		ID:           media.ID,
+		ContentType:  media.ContentType,
+		Width:        media.Width,
+		Height:       media.Height,
+		URL:          signMediaURL(media.ID, mediaVariantOriginal, expires),
+		ThumbnailURL: signMediaURL(media.ID, mediaVariantThumbnail, expires),
+	}
+}
+
+// signMediaURL returns a link to the media which is valid until expires. Media endpoints don't require a session,
+// the signature is the permission to read.
+func signMediaURL(id primitive.ObjectID, variant string, expires time.Time) string {
+	path := "/media/" + id.Hex()
+	if variant == mediaVariantThumbnail {
+		path += "/thumbnail"
+	}
+
+	unix := strconv.FormatInt(expires.Unix(), 10)
+	return fmt.Sprintf("%s?expires=%s&signature=%s", path, unix, mediaSignature(id, variant, unix))
+}
+
+// verifyMediaSignature checks that the query of a media URL is signed and not expired.
+func verifyMediaSignature(id primitive.ObjectID, variant string, expires string, signature string) bool {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(mediaSignature(id, variant, expires)))
+}
+
+func mediaSignature(id primitive.ObjectID, variant string, expires string) string {
+	mac := hmac.New(sha256.New, mediaURLSecret)
+	mac.Write([]byte(id.Hex() + ":" + variant + ":" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
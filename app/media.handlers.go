@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// UploadMediaHandler godoc
+// @Summary      Upload image
+// @Description  JPEG, PNG or GIF up to MEDIA_MAX_SIZE bytes, animated GIFs are rejected. Metadata is stripped, JPEGs are turned upright by their EXIF orientation. Attach the returned ID to a post within a day or the upload is removed.
+// @Tags         media
+// @Accept       mpfd
+// @Produce      json
+// @Param        file   formData      file  true  "Image"
+// @Success      201  {object}  main.MediaView
+// @Router       /media [post]
+func UploadMediaHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	data, ok := readUploadedFile(w, r)
+	if !ok {
+		return
+	}
+
+	media, err := storeMedia(context.Background(), userID, data)
+	if errors.Is(err, errUnsupportedMedia) || errors.Is(err, errAnimatedImage) {
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	} else if errors.Is(err, errMediaTooLarge) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newMediaView(media))
+}
+
+// readUploadedFile reads the file field of a multipart request limited to MEDIA_MAX_SIZE.
+// Writes the error response and returns false when there is no acceptable file.
+func readUploadedFile(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	// Leave some room for the multipart envelope
+	r.Body = http.MaxBytesReader(w, r.Body, mediaMaxSize+64*1024)
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			http.Error(w, errMediaTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return nil, false
+		}
+		http.Error(w, "Missing file", http.StatusBadRequest)
+		return nil, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, mediaMaxSize+1))
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusBadRequest)
+		return nil, false
+	}
+	if int64(len(data)) > mediaMaxSize {
+		http.Error(w, errMediaTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+
+	return data, true
+}
+
+// GetMediaHandler godoc
+// @Summary      Get image
+// @Description  Links are signed and returned in media of posts, no session needed
+// @Tags         media
+// @Produce      image/jpeg,image/png
+// @Param        id   path      string  true  "ID of media"
+// @Param        expires   query      int  true  "Expiration time of the link"
+// @Param        signature   query      string  true  "Signature of the link"
+// @Router       /media/{id} [get]
+func GetMediaHandler(w http.ResponseWriter, r *http.Request) {
+	getMedia(w, r, mediaVariantOriginal, "")
+}
+
+// GetMediaThumbnailHandler godoc
+// @Summary      Get image thumbnail
+// @Description  Links are signed and returned in media of posts, no session needed
+// @Tags         media
+// @Produce      image/jpeg,image/png
+// @Param        id   path      string  true  "ID of media"
+// @Param        expires   query      int  true  "Expiration time of the link"
+// @Param        signature   query      string  true  "Signature of the link"
+// @Router       /media/{id}/thumbnail [get]
+func GetMediaThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	getMedia(w, r, mediaVariantThumbnail, "/thumbnail")
+}
+
+// getMedia streams the variant of the media from the blob store after checking the link signature.
+func getMedia(w http.ResponseWriter, r *http.Request, variant string, suffix string) {
+	mediaID, err := pathObjectID(r.URL.Path, "/media/", suffix)
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	expires := r.URL.Query().Get("expires")
+	if !verifyMediaSignature(mediaID, variant, expires, r.URL.Query().Get("signature")) {
+		http.Error(w, "Invalid or expired link", http.StatusForbidden)
+		return
+	}
+
+	var media Media
+	mediaCollection := mongoClient.Database(dbName).Collection(mediaCollectionName)
+	err = mediaCollection.FindOne(context.Background(), bson.M{"_id": mediaID}).Decode(&media)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	key := media.Key
+	if variant == mediaVariantThumbnail {
+		key = media.ThumbnailKey
+	}
+
+	blob, err := blobStore.Get(r.Context(), key)
+	if errors.Is(err, errBlobNotFound) {
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	// Thumbnails are always encoded in the format of the original
+	unix, _ := strconv.ParseInt(expires, 10, 64)
+	w.Header().Set("Content-Type", media.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", max(0, unix-time.Now().Unix())))
+	io.Copy(w, blob)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testJPEG(t *testing.T, width int, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withExif inserts an APP1 segment with the EXIF payload right after the start of image marker.
+func withExif(data []byte, payload []byte) []byte {
+	exif := append([]byte("Exif\x00\x00"), payload...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(exif)+2))
+	segment = append(segment, exif...)
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func testGIF(t *testing.T, frames int) []byte {
+	palette := color.Palette{color.Black, color.White}
+	animation := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 16, 16), palette)
+		frame.SetColorIndex(i, i, 1)
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 10)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, animation); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessImageRejects(t *testing.T) {
+	// A GIF header announcing 10000x10000 pixels, rejected before decoding
+	huge := []byte("GIF89a")
+	huge = binary.LittleEndian.AppendUint16(huge, 10000)
+	huge = binary.LittleEndian.AppendUint16(huge, 10000)
+	huge = append(huge, 0, 0, 0)
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"text", []byte("hello, not an image"), errUnsupportedMedia},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), errUnsupportedMedia},
+		{"truncated jpeg", testJPEG(t, 8, 8)[:20], errUnsupportedMedia},
+		{"too many pixels", huge, errMediaTooLarge},
+		{"animated gif", testGIF(t, 3), errAnimatedImage},
+	}
+	for _, test := range tests {
+		if _, _, err := processImage(test.data); !errors.Is(err, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestProcessImageStripsExif(t *testing.T) {
+	data := withExif(testJPEG(t, 640, 480), []byte("GPS 52.5200 N 13.4050 E"))
+
+	original, thumbnail, err := processImage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, processed := range []*processedImage{original, thumbnail} {
+		if processed.ContentType != "image/jpeg" {
+			t.Errorf("content type: got %s", processed.ContentType)
+		}
+		if bytes.Contains(processed.Data, []byte("Exif")) || bytes.Contains(processed.Data, []byte("GPS")) {
+			t.Error("EXIF data kept")
+		}
+		if _, err := jpeg.Decode(bytes.NewReader(processed.Data)); err != nil {
+			t.Errorf("re-encoded image doesn't decode: %v", err)
+		}
+	}
+
+	if original.Width != 640 || original.Height != 480 {
+		t.Errorf("original: got %dx%d", original.Width, original.Height)
+	}
+	if thumbnail.Width != thumbnailSize || thumbnail.Height != 240 {
+		t.Errorf("thumbnail: got %dx%d", thumbnail.Width, thumbnail.Height)
+	}
+}
+
+func TestProcessImageOrientation(t *testing.T) {
+	// Big endian TIFF with a single IFD entry: orientation 6, the camera was turned clockwise
+	tiff := []byte("MM\x00\x2A\x00\x00\x00\x08\x00\x01")
+	tiff = append(tiff, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, 0x06, 0x00, 0x00)
+	tiff = append(tiff, 0x00, 0x00, 0x00, 0x00)
+	data := withExif(testJPEG(t, 640, 480), tiff)
+
+	if orientation := jpegOrientation(data); orientation != 6 {
+		t.Fatalf("orientation: got %d", orientation)
+	}
+
+	original, thumbnail, err := processImage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if original.Width != 480 || original.Height != 640 {
+		t.Errorf("original: got %dx%d", original.Width, original.Height)
+	}
+	if thumbnail.Width != 240 || thumbnail.Height != thumbnailSize {
+		t.Errorf("thumbnail: got %dx%d", thumbnail.Width, thumbnail.Height)
+	}
+
+	// The bottom left corner of the stored image is shown top left
+	img, err := jpeg.Decode(bytes.NewReader(original.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, g, _, _ := img.At(0, 0).RGBA()
+	if r>>8 > 16 || g>>8 < 207 {
+		t.Errorf("top left pixel: got r=%d g=%d, want the bottom left one of the upload", r>>8, g>>8)
+	}
+}
+
+func TestProcessImageKeepsStillGIF(t *testing.T) {
+	original, _, err := processImage(testGIF(t, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if original.ContentType != "image/png" {
+		t.Errorf("content type: got %s", original.ContentType)
+	}
+	if _, err := png.Decode(bytes.NewReader(original.Data)); err != nil {
+		t.Errorf("re-encoded image doesn't decode: %v", err)
+	}
+}
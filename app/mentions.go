@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+)
+
+const maxMentionsPerPost = 20
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([\p{L}\p{N}_]+)`)
+
+// parseMentionNames extracts unique @usernames from content in order of appearance.
+func parseMentionNames(content string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		name := match[1]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+		if len(names) == maxMentionsPerPost {
+			break
+		}
+	}
+	return names
+}
+
+// resolveMentions looks up users mentioned in content of the author. Unknown names are ignored, and so are users
+// blocked by the author or blocking them, so they are neither notified nor granted visibility.
+// Mentions keep the user ID, so they still point to the right user after a rename.
+func resolveMentions(ctx context.Context, authorID primitive.ObjectID, content string) ([]Mention, error) {
+	mentions := []Mention{}
+
+	names := parseMentionNames(content)
+	if len(names) == 0 {
+		return mentions, nil
+	}
+
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		keys = append(keys, usernameKey(name))
+	}
+
+	var users []UserSummary
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	projection := options.Find().SetProjection(bson.M{"name": 1})
+	cursor, err := userCollection.Find(ctx, bson.M{"nameKey": bson.M{"$in": keys}}, projection)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]primitive.ObjectID, 0, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+	blocked, err := blockedBetween(ctx, authorID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mentions keep the name as written, so it's linked when rendered
+	idsByKey := make(map[string]primitive.ObjectID, len(users))
+	for _, user := range users {
+		if !blocked[user.ID] {
+			idsByKey[usernameKey(user.Name)] = user.ID
+		}
+	}
+	for _, name := range names {
+		if userID, ok := idsByKey[usernameKey(name)]; ok {
+			mentions = append(mentions, Mention{UserID: userID, Name: name})
+		}
+	}
+
+	return mentions, nil
+}
+
+// notifyMentioned notifies users mentioned in the post, except the ones already mentioned in previous
+// or already notified about the post, so running it again notifies nobody twice.
+// Mentions in private posts are invisible to the mentioned users, so they aren't notified.
+func notifyMentioned(ctx context.Context, post *Post, previous []Mention) error {
+	if post.Visibility == visibilityPrivate {
+		return nil
+	}
+
+	notified := map[primitive.ObjectID]bool{post.Author: true}
+	for _, mention := range previous {
+		notified[mention.UserID] = true
+	}
+
+	var notifications []Notification
+	notificationCollection := mongoClient.Database(dbName).Collection(notificationsCollectionName)
+	cursor, err := notificationCollection.Find(
+		ctx,
+		bson.M{"postId": post.ID, "type": notificationTypeMention},
+		options.Find().SetProjection(bson.M{"userId": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return err
+	}
+	for _, notification := range notifications {
+		notified[notification.UserID] = true
+	}
+
+	for _, mention := range post.Mentions {
+		if notified[mention.UserID] {
+			continue
+		}
+		notified[mention.UserID] = true
+
+		err := createNotification(ctx, &Notification{
+			UserID: mention.UserID,
+			Type:   notificationTypeMention,
+			PostID: post.ID,
+			Actor:  post.Author,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
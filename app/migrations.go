@@ -0,0 +1,471 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+type migration struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// migrations are applied once, in order. Never reorder or rename existing entries.
+var migrations = []migration{
+	{name: "notifications-recipient", run: migrateNotificationsRecipient},
+	{name: "posts-timestamps", run: migratePostsTimestamps},
+	{name: "likes-to-reactions", run: migrateLikesToReactions},
+	{name: "posts-tags", run: migratePostsTags},
+	{name: "users-avatar-uploads", run: migrateUsersAvatarUploads},
+	{name: "posts-visibility", run: migratePostsVisibility},
+	{name: "posts-content-html", run: migratePostsContentHTML},
+	{name: "users-name-key", run: migrateUsersNameKey},
+}
+
+const (
+	// A running migration renews its lease, a replica which stopped renewing it is considered crashed
+	migrationLease = time.Minute
+	// How often replicas check whether a migration run by another replica has finished
+	migrationPollInterval = 2 * time.Second
+
+	migrationStatusRunning = "running"
+	migrationStatusDone    = "done"
+)
+
+// migrationRecord tracks a migration in the migrations collection. Records written before the status was tracked
+// have no status and are done.
+type migrationRecord struct {
+	Name       string             `bson:"_id"`
+	Status     string             `bson:"status,omitempty"`
+	Owner      primitive.ObjectID `bson:"owner,omitempty"`
+	LeaseUntil *time.Time         `bson:"leaseUntil,omitempty"`
+	AppliedAt  *time.Time         `bson:"appliedAt,omitempty"`
+}
+
+// runMigrations applies every migration that isn't done yet, in order. A replica claims a migration with a lease
+// before running it, other replicas wait for it to finish before going on to the next ones. When the lease
+// of a crashed replica expires the migration is claimed and run again, so migrations must be safe to rerun.
+func runMigrations(ctx context.Context) error {
+	collection := mongoClient.Database(dbName).Collection(migrationsCollectionName)
+	owner := primitive.NewObjectID()
+
+	for _, m := range migrations {
+		claimed, err := waitForMigration(ctx, collection, m.name, owner)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		log.Printf(">>> Running migration %s ...\n", m.name)
+		if err := runClaimedMigration(ctx, collection, m, owner); err != nil {
+			// Release the claim, so the migration is retried right away on the next start
+			collection.DeleteOne(ctx, bson.M{"_id": m.name, "owner": owner})
+			return err
+		}
+	}
+
+	return nil
+}
+
+// waitForMigration claims the migration for the owner and returns true, or returns false once it's done.
+// It waits while another replica holds the lease.
+func waitForMigration(ctx context.Context, collection *mongo.Collection, name string, owner primitive.ObjectID) (bool, error) {
+	for {
+		leaseUntil := time.Now().Add(migrationLease)
+		_, err := collection.InsertOne(ctx, migrationRecord{Name: name, Status: migrationStatusRunning, Owner: owner, LeaseUntil: &leaseUntil})
+		if err == nil {
+			return true, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return false, err
+		}
+
+		var record migrationRecord
+		err = collection.FindOne(ctx, bson.M{"_id": name}).Decode(&record)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// Released by a failed run in the meantime
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if record.Status != migrationStatusRunning {
+			return false, nil
+		}
+
+		if record.LeaseUntil == nil || record.LeaseUntil.Before(time.Now()) {
+			// The matching lease makes only one of the replicas waiting for it take the migration over
+			result, err := collection.UpdateOne(
+				ctx,
+				bson.M{"_id": name, "status": migrationStatusRunning, "leaseUntil": record.LeaseUntil},
+				bson.M{"$set": bson.M{"owner": owner, "leaseUntil": leaseUntil}},
+			)
+			if err != nil {
+				return false, err
+			}
+			if result.ModifiedCount > 0 {
+				log.Printf(">>> Migration %s was interrupted, running it again\n", name)
+				return true, nil
+			}
+			continue
+		}
+
+		log.Printf(">>> Waiting for migration %s run by another replica ...\n", name)
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(migrationPollInterval):
+		}
+	}
+}
+
+// runClaimedMigration runs the migration while renewing the lease of the owner and marks it done.
+func runClaimedMigration(ctx context.Context, collection *mongo.Collection, m migration, owner primitive.ObjectID) error {
+	renewCtx, stopRenewing := context.WithCancel(ctx)
+	defer stopRenewing()
+	go func() {
+		ticker := time.NewTicker(migrationLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-renewCtx.Done():
+				return
+			case <-ticker.C:
+				_, err := collection.UpdateOne(
+					renewCtx,
+					bson.M{"_id": m.name, "owner": owner},
+					bson.M{"$set": bson.M{"leaseUntil": time.Now().Add(migrationLease)}},
+				)
+				if err != nil && renewCtx.Err() == nil {
+					log.Printf(">>> Failed to renew lease of migration %s: %v\n", m.name, err)
+				}
+			}
+		}
+	}()
+
+	if err := m.run(ctx); err != nil {
+		return err
+	}
+	stopRenewing()
+
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": m.name, "owner": owner},
+		bson.M{
+			"$set":   bson.M{"status": migrationStatusDone, "appliedAt": time.Now()},
+			"$unset": bson.M{"owner": "", "leaseUntil": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("lease of migration %s was lost while running it", m.name)
+	}
+	return nil
+}
+
+// migrateNotificationsRecipient sets the recipient of old like notifications to the author of the liked post.
+func migrateNotificationsRecipient(ctx context.Context) error {
+	notificationCollection := mongoClient.Database(dbName).Collection(notificationsCollectionName)
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+
+	cursor, err := notificationCollection.Find(ctx, bson.M{"userId": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var notification Notification
+		if err := cursor.Decode(&notification); err != nil {
+			return err
+		}
+
+		var post Post
+		err := postsCollection.FindOne(ctx, bson.M{"_id": notification.PostID}).Decode(&post)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// The liked post is gone, nobody is left to notify
+			if _, err := notificationCollection.DeleteOne(ctx, bson.M{"_id": notification.ID}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = notificationCollection.UpdateOne(ctx, bson.M{"_id": notification.ID}, bson.M{"$set": bson.M{
+			"userId":    post.Author,
+			"read":      false,
+			"createdAt": notification.ID.Timestamp(),
+		}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// migratePostsTimestamps backfills timestamps of posts created before they were tracked from their ObjectIDs,
+// and records their current content as the first revision.
+func migratePostsTimestamps(ctx context.Context) error {
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	filter := bson.M{"createdAt": bson.M{"$exists": false}}
+
+	cursor, err := postsCollection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var post Post
+		if err := cursor.Decode(&post); err != nil {
+			return err
+		}
+
+		// Like in finishPost the first revision shares the ID of the post, so a rerun doesn't record it twice
+		err := insertPostRevision(ctx, PostRevision{
+			ID:        post.ID,
+			PostID:    post.ID,
+			Content:   post.Content,
+			EditedBy:  post.Author,
+			CreatedAt: post.ID.Timestamp(),
+		})
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	_, err = postsCollection.UpdateMany(ctx, filter, bson.A{
+		bson.M{"$set": bson.M{
+			"createdAt": bson.M{"$toDate": "$_id"},
+			"updatedAt": bson.M{"$toDate": "$_id"},
+		}},
+	})
+	return err
+}
+
+// migrateLikesToReactions turns likes stored in users' likedPosts into reactions of the default type.
+func migrateLikesToReactions(ctx context.Context) error {
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	reactionCollection := mongoClient.Database(dbName).Collection(reactionsCollectionName)
+
+	cursor, err := userCollection.Find(ctx, bson.M{"likedPosts.0": bson.M{"$exists": true}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var user struct {
+			ID         primitive.ObjectID   `bson:"_id"`
+			LikedPosts []primitive.ObjectID `bson:"likedPosts"`
+		}
+		if err := cursor.Decode(&user); err != nil {
+			return err
+		}
+
+		posts := findPostsByIDs(ctx, user.LikedPosts)
+		for _, postID := range user.LikedPosts {
+			post, ok := posts[postID]
+			if !ok {
+				continue
+			}
+
+			_, err := reactionCollection.UpdateOne(
+				ctx,
+				bson.M{"postId": post.ID, "userId": user.ID},
+				bson.M{"$setOnInsert": bson.M{
+					"_id":        primitive.NewObjectID(),
+					"postAuthor": post.Author,
+					"type":       defaultReaction,
+					"createdAt":  time.Now(),
+				}},
+				options.Update().SetUpsert(true),
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	_, err = postsCollection.UpdateMany(ctx, bson.M{"reactionCounts": bson.M{"$exists": false}}, bson.A{
+		bson.M{"$set": bson.M{"reactionCounts": bson.M{defaultReaction: "$likesCount"}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = userCollection.UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"likedPosts": ""}})
+	return err
+}
+
+// migratePostsTags parses tags of posts created before tags were tracked.
+func migratePostsTags(ctx context.Context) error {
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	cursor, err := postsCollection.Find(ctx, bson.M{"tags": bson.M{"$exists": false}}, options.Find().SetProjection(bson.M{"content": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var post Post
+		if err := cursor.Decode(&post); err != nil {
+			return err
+		}
+
+		_, err := postsCollection.UpdateOne(ctx, bson.M{"_id": post.ID}, bson.M{"$set": bson.M{"tags": parseTags(post.Content)}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// migrateUsersAvatarUploads drops free-form avatar strings set by clients before avatars were uploaded.
+func migrateUsersAvatarUploads(ctx context.Context) error {
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	_, err := userCollection.UpdateMany(ctx, bson.M{"avatar": bson.M{"$type": "string"}}, bson.M{"$unset": bson.M{"avatar": ""}})
+	return err
+}
+
+// migratePostsVisibility makes posts created before visibility was tracked public, as they have always been.
+func migratePostsVisibility(ctx context.Context) error {
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	_, err := postsCollection.UpdateMany(ctx, bson.M{"visibility": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"visibility": visibilityPublic}})
+	return err
+}
+
+// migratePostsContentHTML renders content of posts created before it was rendered. The length limit isn't enforced
+// on posts which were already accepted.
+func migratePostsContentHTML(ctx context.Context) error {
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	projection := options.Find().SetProjection(bson.M{"content": 1, "mentions": 1})
+	cursor, err := postsCollection.Find(ctx, bson.M{"contentHtml": bson.M{"$exists": false}}, projection)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var post Post
+		if err := cursor.Decode(&post); err != nil {
+			return err
+		}
+
+		contentHTML := renderContent(post.Content, post.Mentions)
+		_, err := postsCollection.UpdateOne(ctx, bson.M{"_id": post.ID}, bson.M{"$set": bson.M{"contentHtml": contentHTML}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// migrateUsersNameKey sets the key names are unique by. Users whose names differ only in case from the name
+// of an older user, or duplicate it, are renamed by appending a number and the renames are logged,
+// so the unique index can be built.
+func migrateUsersNameKey(ctx context.Context) error {
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	projection := options.Find().SetProjection(bson.M{"name": 1}).SetSort(bson.M{"_id": 1})
+	cursor, err := userCollection.Find(ctx, bson.M{}, projection)
+	if err != nil {
+		return err
+	}
+	var users []User
+	if err := cursor.All(ctx, &users); err != nil {
+		return err
+	}
+
+	taken := make(map[string]bool, len(users))
+	for _, user := range users {
+		taken[usernameKey(user.Name)] = true
+	}
+
+	kept := make(map[string]bool, len(users))
+	for _, user := range users {
+		name := user.Name
+		if kept[usernameKey(name)] {
+			name = freeUsername(user.Name, taken)
+			log.Printf(">>> Renamed user %s from %s to %s, the name was taken\n", user.ID.Hex(), user.Name, name)
+		}
+		taken[usernameKey(name)] = true
+		kept[usernameKey(name)] = true
+
+		_, err := userCollection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"name": name, "nameKey": usernameKey(name)}})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Reserved names were kept as written
+	reservedNameCollection := mongoClient.Database(dbName).Collection(reservedNamesCollectionName)
+	cursor, err = reservedNameCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	var reservedNames []ReservedName
+	if err := cursor.All(ctx, &reservedNames); err != nil {
+		return err
+	}
+	for _, reserved := range reservedNames {
+		key := usernameKey(reserved.Key)
+		if key == reserved.Key {
+			continue
+		}
+		if !taken[key] {
+			taken[key] = true
+			_, err := reservedNameCollection.UpdateOne(
+				ctx,
+				bson.M{"_id": key},
+				bson.M{"$setOnInsert": bson.M{"userId": reserved.UserID, "expiresAt": reserved.ExpiresAt}},
+				options.Update().SetUpsert(true),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := reservedNameCollection.DeleteOne(ctx, bson.M{"_id": reserved.Key}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// freeUsername appends the lowest number to name which makes it free, shortening the name to fit when needed.
+func freeUsername(name string, taken map[string]bool) string {
+	for n := 2; ; n++ {
+		suffix := fmt.Sprintf("_%d", n)
+		base := []rune(name)
+		if len(base)+len(suffix) > maxUsernameLength {
+			base = base[:maxUsernameLength-len(suffix)]
+		}
+		candidate := string(base) + suffix
+		if !taken[usernameKey(candidate)] {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFreeUsername(t *testing.T) {
+	taken := map[string]bool{"alice": true, "alice_2": true}
+	if name := freeUsername("Alice", taken); name != "Alice_3" {
+		t.Errorf("got %q, want Alice_3", name)
+	}
+
+	long := strings.Repeat("é", maxUsernameLength)
+	name := freeUsername(long, map[string]bool{usernameKey(long): true})
+	if len([]rune(name)) != maxUsernameLength || !strings.HasSuffix(name, "_2") {
+		t.Errorf("got %q, want the name shortened to fit _2", name)
+	}
+	if _, err := parseUsername(name); err != nil {
+		t.Errorf("renamed user got an invalid name: %v", err)
+	}
+}
@@ -2,28 +2,234 @@ package main
 
 import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	roleModerator = "moderator"
+)
+
+const (
+	postKindPost   = "post"
+	postKindRepost = "repost"
+	postKindQuote  = "quote"
+)
+
+const (
+	digestScheduleOff    = "off"
+	digestScheduleDaily  = "daily"
+	digestScheduleWeekly = "weekly"
 )
 
 type User struct {
-	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
-	Name          string               `bson:"name" json:"name"`
-	Password      string               `bson:"password" json:"password"`
-	Avatar        string               `bson:"avatar" json:"avatar"`
-	Posts         []primitive.ObjectID `bson:"posts" json:"posts"`
-	LikedPosts    []primitive.ObjectID `bson:"likedPosts" json:"likedPosts"`
-	Notifications []primitive.ObjectID `bson:"notifications" json:"notifications"`
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name           string             `bson:"name" json:"name"`
+	NameKey        string             `bson:"nameKey" json:"-"` // Lowercase name, names are unique regardless of case
+	NameChangedAt  *time.Time         `bson:"nameChangedAt,omitempty" json:"nameChangedAt,omitempty"`
+	Password       string             `bson:"password" json:"-"`
+	Role           string             `bson:"role" json:"role"`
+	DisplayName    string             `bson:"displayName,omitempty" json:"displayName,omitempty"`
+	Avatar         *Avatar            `bson:"avatar,omitempty" json:"avatar,omitempty" swaggertype:"object,string"`
+	Bio            string             `bson:"bio,omitempty" json:"bio,omitempty"`
+	Location       string             `bson:"location,omitempty" json:"location,omitempty"`
+	Links          []string           `bson:"links,omitempty" json:"links,omitempty"` // Websites
+	Email          string             `bson:"email" json:"email"`
+	DigestSchedule string             `bson:"digestSchedule" json:"digestSchedule"`
+	// Preferences for reading posts, collapsed parts are left out of post listings
+	ExpandContentWarnings bool                 `bson:"expandContentWarnings" json:"expandContentWarnings"`
+	ShowSensitiveMedia    bool                 `bson:"showSensitiveMedia" json:"showSensitiveMedia"`
+	LastSignInAt          time.Time            `bson:"lastSignInAt" json:"lastSignInAt"`
+	FollowersCount        int                  `bson:"followersCount" json:"followersCount"`
+	FollowingCount        int                  `bson:"followingCount" json:"followingCount"`
+	Posts                 []primitive.ObjectID `bson:"posts" json:"posts"`
+	PinnedPosts           []primitive.ObjectID `bson:"pinnedPosts,omitempty" json:"pinnedPosts,omitempty"` // Most recently pinned first
+	Notifications         []primitive.ObjectID `bson:"notifications" json:"notifications"`
+	SuspendedAt           *time.Time           `bson:"suspendedAt,omitempty" json:"suspendedAt,omitempty"` // Set by a moderator
 }
 
 type Post struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Content    string             `bson:"content" json:"content"`
-	Author     primitive.ObjectID `bson:"author" json:"author"`
-	LikesCount int                `bson:"likesCount" json:"likesCount"`
+	ID                  primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Kind                string               `bson:"kind" json:"kind" enums:"post,repost,quote"`
+	Visibility          string               `bson:"visibility" json:"visibility" enums:"public,followers,mentioned,private"`
+	Content             string               `bson:"content" json:"content"` // Markdown source
+	ContentHTML         string               `bson:"contentHtml" json:"contentHtml"`
+	ContentWarning      string               `bson:"contentWarning,omitempty" json:"contentWarning,omitempty"`
+	Sensitive           bool                 `bson:"sensitive" json:"sensitive"`                                 // Attached media is sensitive
+	SensitiveLocked     bool                 `bson:"sensitiveLocked,omitempty" json:"sensitiveLocked,omitempty"` // Set by a moderator, the author can't change it then
+	Tags                []string             `bson:"tags" json:"tags"`
+	Mentions            []Mention            `bson:"mentions" json:"mentions"`
+	Media               []primitive.ObjectID `bson:"media,omitempty" json:"-"`
+	Poll                *Poll                `bson:"poll,omitempty" json:"-"` // Rendered by PostView, results depend on the viewer
+	Preview             *LinkPreview         `bson:"preview,omitempty" json:"preview,omitempty"`
+	PreviewURL          string               `bson:"previewUrl,omitempty" json:"-"` // Link waiting for its preview, see attachLinkPreviews
+	PreviewClaimedUntil *time.Time           `bson:"previewClaimedUntil,omitempty" json:"-"`
+	Author              primitive.ObjectID   `bson:"author" json:"author"`
+	OriginalID          *primitive.ObjectID  `bson:"originalId,omitempty" json:"originalId,omitempty" swaggertype:"string"` // Post that is reposted or quoted
+	RepostsCount        int                  `bson:"repostsCount" json:"repostsCount"`
+	QuotesCount         int                  `bson:"quotesCount" json:"quotesCount"`
+	LikesCount          int                  `bson:"likesCount" json:"likesCount"`
+	ReactionCounts      map[string]int       `bson:"reactionCounts" json:"reactionCounts"` // Likes included
+	CommentsCount       int                  `bson:"commentsCount" json:"commentsCount"`
+	CreatedAt           time.Time            `bson:"createdAt" json:"createdAt"`
+	UpdatedAt           time.Time            `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Draft is a post which isn't published yet. Scheduled drafts are published by the scheduler at PublishAt,
+// the others when their author decides to.
+type Draft struct {
+	ID           primitive.ObjectID   `bson:"_id" json:"id"`
+	Author       primitive.ObjectID   `bson:"author" json:"author"`
+	Content      string               `bson:"content" json:"content"`
+	Visibility   string               `bson:"visibility" json:"visibility" enums:"public,followers,mentioned,private"`
+	Media        []primitive.ObjectID `bson:"media,omitempty" json:"media,omitempty"`
+	PublishAt    *time.Time           `bson:"publishAt,omitempty" json:"publishAt,omitempty"`
+	PostID       *primitive.ObjectID  `bson:"postId,omitempty" json:"-"`                            // ID of the post, reserved by the first publishing attempt
+	ClaimedUntil *time.Time           `bson:"claimedUntil,omitempty" json:"-"`                      // Set while a publishing attempt is running
+	PublishError string               `bson:"publishError,omitempty" json:"publishError,omitempty"` // Why the draft couldn't be published, it's unscheduled until edited
+	CreatedAt    time.Time            `bson:"createdAt" json:"createdAt"`
+	UpdatedAt    time.Time            `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Poll attached to a post. Vote counts are kept here, votes themselves in their own collection.
+type Poll struct {
+	Options     []PollOption `bson:"options"`
+	Multiple    bool         `bson:"multiple"`
+	ClosesAt    time.Time    `bson:"closesAt"`
+	VotersCount int          `bson:"votersCount"`
+	Closed      bool         `bson:"closed"` // Set by the job notifying about the closing
+}
+
+type PollOption struct {
+	Text       string `bson:"text"`
+	VotesCount int    `bson:"votesCount"`
+}
+
+// PollVote holds the options a user chose in a poll, by index. A user has at most one vote per poll.
+type PollVote struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PostID    primitive.ObjectID `bson:"postId" json:"postId"`
+	UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+	Choices   []int              `bson:"choices" json:"choices"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Mention of a user in post content. Name is kept as it was written, the link to the user goes by UserID.
+type Mention struct {
+	UserID primitive.ObjectID `bson:"userId" json:"userId"`
+	Name   string             `bson:"name" json:"name"`
+}
+
+// PostRevision is a version of post content. Revisions are append-only, the newest one matches the post.
+type PostRevision struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PostID    primitive.ObjectID `bson:"postId" json:"postId"`
+	Content   string             `bson:"content" json:"content"`
+	EditedBy  primitive.ObjectID `bson:"editedBy" json:"editedBy"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
 }
 
 type Notification struct {
-	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Type    string             `bson:"type" json:"type"`
-	PostID  primitive.ObjectID `bson:"postId" json:"postId"`
-	LikedBy primitive.ObjectID `bson:"likedBy" json:"likedBy"`
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+	Type      string             `bson:"type" json:"type"`
+	PostID    primitive.ObjectID `bson:"postId" json:"postId"`
+	LikedBy   primitive.ObjectID `bson:"likedBy" json:"likedBy"`
+	Actor     primitive.ObjectID `bson:"actorId" json:"actorId"`
+	CommentID primitive.ObjectID `bson:"commentId,omitempty" json:"commentId,omitempty"`
+	Reaction  string             `bson:"reaction,omitempty" json:"reaction,omitempty"`
+	Read      bool               `bson:"read" json:"read"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// Reaction of a user to a post. A user has at most one reaction per post.
+type Reaction struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PostID     primitive.ObjectID `bson:"postId" json:"postId"`
+	PostAuthor primitive.ObjectID `bson:"postAuthor" json:"postAuthor"`
+	UserID     primitive.ObjectID `bson:"userId" json:"userId"`
+	Type       string             `bson:"type" json:"type"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// Comment on a post. Replies reference their parent comment and the root comment of their thread,
+// so that whole threads can be loaded with a single query.
+type Comment struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	PostID    primitive.ObjectID  `bson:"postId" json:"postId"`
+	ParentID  *primitive.ObjectID `bson:"parentId,omitempty" json:"parentId,omitempty" swaggertype:"string"`
+	RootID    *primitive.ObjectID `bson:"rootId,omitempty" json:"rootId,omitempty" swaggertype:"string"`
+	Author    primitive.ObjectID  `bson:"author" json:"author"`
+	Content   string              `bson:"content" json:"content"`
+	Deleted   bool                `bson:"deleted" json:"deleted"`
+	CreatedAt time.Time           `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time           `bson:"updatedAt" json:"updatedAt"`
+	// Replies are only filled in the tree view
+	Replies []*Comment `bson:"-" json:"replies,omitempty"`
+}
+
+// Follow is an edge of the social graph: Follower follows Followee.
+// Edges live in their own collection so that users with many followers don't grow unbounded documents.
+type Follow struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Follower  primitive.ObjectID `bson:"follower" json:"follower"`
+	Followee  primitive.ObjectID `bson:"followee" json:"followee"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// UserSummary is the public part of a user shown in lists.
+type UserSummary struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	DisplayName string             `bson:"displayName,omitempty" json:"displayName,omitempty"`
+	Avatar      *Avatar            `bson:"avatar,omitempty" json:"avatar,omitempty" swaggertype:"object,string"`
+}
+
+// Media is an uploaded image. It belongs to the uploader until it's attached to a post.
+type Media struct {
+	ID           primitive.ObjectID  `bson:"_id" json:"id"`
+	Owner        primitive.ObjectID  `bson:"owner" json:"owner"`
+	PostID       *primitive.ObjectID `bson:"postId,omitempty" json:"postId,omitempty" swaggertype:"string"` // Post or draft
+	ContentType  string              `bson:"contentType" json:"contentType"`
+	Size         int                 `bson:"size" json:"size"`
+	Width        int                 `bson:"width" json:"width"`
+	Height       int                 `bson:"height" json:"height"`
+	Key          string              `bson:"key" json:"-"`
+	ThumbnailKey string              `bson:"thumbnailKey" json:"-"`
+	CreatedAt    time.Time           `bson:"createdAt" json:"createdAt"`
+}
+
+// Bookmark is a post saved by a user, private to that user. A post is bookmarked once and can be filed in one collection.
+type Bookmark struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID       primitive.ObjectID  `bson:"userId" json:"userId"`
+	PostID       primitive.ObjectID  `bson:"postId" json:"postId"`
+	CollectionID *primitive.ObjectID `bson:"collectionId,omitempty" json:"collectionId,omitempty" swaggertype:"string"`
+	CreatedAt    time.Time           `bson:"createdAt" json:"createdAt"`
+}
+
+// BookmarkCollection is a named folder of bookmarks.
+type BookmarkCollection struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Owner     primitive.ObjectID `bson:"owner" json:"owner"`
+	Name      string             `bson:"name" json:"name"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// Digest records that a digest for the given period was sent to a user.
+// The unique index on (userId, period) is what keeps the digest job idempotent.
+type Digest struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+	Period    string             `bson:"period" json:"period"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// reactionsCount is the total number of reactions of all types.
+func (p Post) reactionsCount() int {
+	total := 0
+	for _, count := range p.ReactionCounts {
+		total += count
+	}
+	return total
 }
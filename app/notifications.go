@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	notificationTypeLike   = "like"
+	notificationTypeFollow = "follow"
+	// Reaction other than like to a post of the recipient
+	notificationTypeReaction = "reaction"
+	// The recipient is mentioned in a post
+	notificationTypeMention = "mention"
+	// Comment on a post of the recipient
+	notificationTypeComment = "comment"
+	// Reply to a comment of the recipient
+	notificationTypeReply = "reply"
+	// Poll of a post the recipient wrote or voted in has closed
+	notificationTypePollClosed = "poll_closed"
+)
+
+// createNotification stores the notification and links it to its recipient (notification.UserID).
+func createNotification(ctx context.Context, notification *Notification) error {
+	if notification.ID.IsZero() {
+		notification.ID = primitive.NewObjectID()
+	}
+	notification.Read = false
+	notification.CreatedAt = time.Now()
+
+	notificationCollection := mongoClient.Database(dbName).Collection(notificationsCollectionName)
+	_, err := notificationCollection.InsertOne(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	_, err = userCollection.UpdateOne(ctx, bson.M{"_id": notification.UserID}, bson.M{"$addToSet": bson.M{"notifications": notification.ID}})
+	return err
+}
+
+// visibleNotifications drops notifications about posts the viewer can no longer see,
+// e.g. after the author narrowed visibility or the viewer unfollowed them, and ones caused by blocked or muted users.
+func visibleNotifications(ctx context.Context, viewer *Viewer, notifications []Notification) []Notification {
+	postIDs := make([]primitive.ObjectID, 0, len(notifications))
+	for _, notification := range notifications {
+		if !notification.PostID.IsZero() {
+			postIDs = append(postIDs, notification.PostID)
+		}
+	}
+	posts := findPostsByIDs(ctx, postIDs)
+
+	result := make([]Notification, 0, len(notifications))
+	for _, notification := range notifications {
+		if post, ok := posts[notification.PostID]; ok && !viewer.canView(&post) {
+			continue
+		}
+		if !viewer.canSee(notification.Actor) || !viewer.canSee(notification.LikedBy) {
+			continue
+		}
+		result = append(result, notification)
+	}
+
+	return result
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Page is the envelope of every paginated list. Next points to older items, Prev to newer ones.
+type Page[T any] struct {
+	Items []T    `json:"items"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+}
+
+// pageParams are parsed from ?limit=&before=&after= query params.
+// Lists are sorted by an ObjectID key, newest first, so cursors are opaque encodings of that key.
+type pageParams struct {
+	limit  int
+	before primitive.ObjectID
+	after  primitive.ObjectID
+}
+
+func parsePageParams(r *http.Request) (pageParams, error) {
+	query := r.URL.Query()
+	params := pageParams{limit: defaultPageLimit}
+
+	if limitFromQuery := query.Get("limit"); limitFromQuery != "" {
+		limit, err := strconv.Atoi(limitFromQuery)
+		if err != nil || limit <= 0 || limit > maxPageLimit {
+			return params, errors.New("invalid limit")
+		}
+		params.limit = limit
+	}
+
+	var err error
+	if before := query.Get("before"); before != "" {
+		if params.before, err = decodeCursor(before); err != nil {
+			return params, err
+		}
+	}
+	if after := query.Get("after"); after != "" {
+		if params.after, err = decodeCursor(after); err != nil {
+			return params, err
+		}
+	}
+	if !params.before.IsZero() && !params.after.IsZero() {
+		return params, errors.New("before and after can't be used together")
+	}
+
+	return params, nil
+}
+
+func encodeCursor(key primitive.ObjectID) string {
+	return base64.RawURLEncoding.EncodeToString(key[:])
+}
+
+func decodeCursor(cursor string) (primitive.ObjectID, error) {
+	var key primitive.ObjectID
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(raw) != len(key) {
+		return key, errInvalidCursor
+	}
+	copy(key[:], raw)
+	return key, nil
+}
+
+// filter returns the condition on the sort key field selecting the requested page.
+func (p pageParams) filter(field string) bson.M {
+	switch {
+	case !p.before.IsZero():
+		return bson.M{field: bson.M{"$lt": p.before}}
+	case !p.after.IsZero():
+		return bson.M{field: bson.M{"$gt": p.after}}
+	default:
+		return bson.M{}
+	}
+}
+
+// findOptions sorts by the key field and fetches one extra item to detect whether there is a next page.
+// Pages requested with after are read in ascending order, use reverse to restore the newest first order.
+func (p pageParams) findOptions(field string) *options.FindOptions {
+	return options.Find().SetSort(p.sort(field)).SetLimit(int64(p.limit + 1))
+}
+
+// sort is the order of the key field used by findOptions, for aggregation pipelines.
+func (p pageParams) sort(field string) bson.D {
+	direction := -1
+	if !p.after.IsZero() {
+		direction = 1
+	}
+	return bson.D{{Key: field, Value: direction}}
+}
+
+// newPage trims the extra item fetched by findOptions and builds the envelope with links to the neighbour pages.
+func newPage[T any](r *http.Request, p pageParams, items []T, key func(T) primitive.ObjectID) Page[T] {
+	hasMore := len(items) > p.limit
+	if hasMore {
+		items = items[:p.limit]
+	}
+	if !p.after.IsZero() {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	if items == nil {
+		items = []T{}
+	}
+
+	page := Page[T]{Items: items}
+	if len(items) == 0 {
+		return page
+	}
+
+	// Going back in time is only possible when there is more data, or when we came from the newer side
+	if (hasMore && p.after.IsZero()) || !p.after.IsZero() {
+		page.Next = pageLink(r, p, "before", key(items[len(items)-1]))
+	}
+	if (hasMore && !p.after.IsZero()) || !p.before.IsZero() {
+		page.Prev = pageLink(r, p, "after", key(items[0]))
+	}
+
+	return page
+}
+
+func pageLink(r *http.Request, p pageParams, direction string, key primitive.ObjectID) string {
+	query := url.Values{}
+	for name, values := range r.URL.Query() {
+		if name != "before" && name != "after" {
+			query[name] = values
+		}
+	}
+	query.Set("limit", strconv.Itoa(p.limit))
+	query.Set(direction, encodeCursor(key))
+
+	link := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return link.String()
+}
+
+func postKey(post Post) primitive.ObjectID {
+	return post.ID
+}
+
+func notificationKey(notification Notification) primitive.ObjectID {
+	return notification.ID
+}
+
+func followKey(follow Follow) primitive.ObjectID {
+	return follow.ID
+}
+
+func postRevisionKey(revision PostRevision) primitive.ObjectID {
+	return revision.ID
+}
+
+func commentKey(comment *Comment) primitive.ObjectID {
+	return comment.ID
+}
+
+func reactionKey(reaction Reaction) primitive.ObjectID {
+	return reaction.ID
+}
+
+func bookmarkKey(bookmark Bookmark) primitive.ObjectID {
+	return bookmark.ID
+}
+
+func bookmarkCollectionKey(collection BookmarkCollection) primitive.ObjectID {
+	return collection.ID
+}
+
+func draftKey(draft Draft) primitive.ObjectID {
+	return draft.ID
+}
+
+func blockKey(block Block) primitive.ObjectID {
+	return block.ID
+}
+
+func muteKey(mute Mute) primitive.ObjectID {
+	return mute.ID
+}
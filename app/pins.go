@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+var maxPinnedPosts int
+
+var (
+	errAlreadyPinned = errors.New("post is already pinned")
+	errTooManyPinned = errors.New("too many pinned posts")
+	errNotPinned     = errors.New("post is not pinned")
+	errUserNotFound  = errors.New("user not found")
+)
+
+// pinPost puts the post on top of the pinned posts of its author. The limit is checked by the update itself,
+// so concurrent pins can't exceed it.
+func pinPost(ctx context.Context, post *Post) error {
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	result, err := userCollection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":         post.Author,
+			"pinnedPosts": bson.M{"$ne": post.ID},
+			// Matches users with fewer than maxPinnedPosts pins
+			"pinnedPosts." + strconv.Itoa(maxPinnedPosts-1): bson.M{"$exists": false},
+		},
+		bson.M{"$push": bson.M{"pinnedPosts": bson.M{"$each": bson.A{post.ID}, "$position": 0}}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount > 0 {
+		return nil
+	}
+
+	pinned, err := pinnedPostIDs(ctx, post.Author)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(pinned, post.ID) {
+		return errAlreadyPinned
+	}
+	return errTooManyPinned
+}
+
+// unpinPost removes the post from the pinned posts of its author.
+func unpinPost(ctx context.Context, post *Post) error {
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	result, err := userCollection.UpdateOne(ctx, bson.M{"_id": post.Author}, bson.M{"$pull": bson.M{"pinnedPosts": post.ID}})
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return errNotPinned
+	}
+	return nil
+}
+
+// pinnedPostIDs returns IDs of pinned posts of the user, most recently pinned first.
+func pinnedPostIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	var user User
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	err := userCollection.FindOne(ctx, bson.M{"_id": userID}, options.FindOne().SetProjection(bson.M{"pinnedPosts": 1})).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, errUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user.PinnedPosts, nil
+}
+
+// userPostsPage lists posts of the author visible to the viewer, newest first. The first page starts with
+// the pinned posts, which are left out of the rest of the listing so that cursors don't return them again.
+func userPostsPage(ctx context.Context, r *http.Request, viewer *Viewer, authorID primitive.ObjectID, page pageParams) (Page[PostView], error) {
+	pinnedIDs, err := pinnedPostIDs(ctx, authorID)
+	if err != nil {
+		return Page[PostView]{}, err
+	}
+
+	filter := page.filter("_id")
+	if len(pinnedIDs) > 0 {
+		idFilter, ok := filter["_id"].(bson.M)
+		if !ok {
+			idFilter = bson.M{}
+		}
+		idFilter["$nin"] = pinnedIDs
+		filter["_id"] = idFilter
+	}
+	filter["author"] = authorID
+	viewer.restrictPosts(filter)
+
+	var posts []Post
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	cursor, err := postsCollection.Find(ctx, filter, page.findOptions("_id"))
+	if err != nil {
+		return Page[PostView]{}, err
+	}
+	if err := cursor.All(ctx, &posts); err != nil {
+		return Page[PostView]{}, err
+	}
+
+	result := buildPostViewsPage(ctx, viewer, newPage(r, page, posts, postKey))
+	if !page.before.IsZero() || !page.after.IsZero() {
+		return result, nil
+	}
+
+	pinnedByID := findPostsByIDs(ctx, pinnedIDs)
+	pinned := make([]Post, 0, len(pinnedIDs))
+	for _, id := range pinnedIDs {
+		if post, ok := pinnedByID[id]; ok && viewer.canView(&post) {
+			pinned = append(pinned, post)
+		}
+	}
+
+	pinnedViews := buildPostViews(ctx, viewer, pinned)
+	for i := range pinnedViews {
+		pinnedViews[i].Pinned = true
+	}
+	result.Items = append(pinnedViews, result.Items...)
+
+	return result, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// PinPostHandler godoc
+// @Summary      Pin post
+// @Description  Pinned posts head the listings of the author posts, most recently pinned first
+// @Tags         posts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of own post"
+// @Router       /posts/{id}/pin [put]
+func PinPostHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "/pin")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	post, err := findPostByID(context.Background(), postID)
+	if errors.Is(err, errPostNotFound) {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if post.Author != userID {
+		http.Error(w, "Only own posts can be pinned", http.StatusForbidden)
+		return
+	}
+
+	err = pinPost(context.Background(), post)
+	if errors.Is(err, errAlreadyPinned) {
+		http.Error(w, "Post is already pinned", http.StatusConflict)
+		return
+	} else if errors.Is(err, errTooManyPinned) {
+		http.Error(w, fmt.Sprintf("Up to %d posts can be pinned", maxPinnedPosts), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Post pinned successfully"))
+}
+
+// UnpinPostHandler godoc
+// @Summary      Unpin post
+// @Tags         posts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of own post"
+// @Router       /posts/{id}/pin [delete]
+func UnpinPostHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "/pin")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	err = unpinPost(context.Background(), &Post{ID: postID, Author: userID})
+	if errors.Is(err, errNotPinned) {
+		http.Error(w, "Post is not pinned", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Post unpinned successfully"))
+}
+
+// GetUserPostsHandler godoc
+// @Summary      Get posts of user
+// @Description  Only posts visible to me. The first page starts with pinned posts
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Param        limit   query      int  false  "Max number of items to return"
+// @Param        before   query      string  false  "Cursor of the page with older items"
+// @Param        after   query      string  false  "Cursor of the page with newer items"
+// @Success      200  {object}  main.Page[main.PostView]
+// @Router       /users/{id}/posts [get]
+func GetUserPostsHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	authorID, err := pathObjectID(r.URL.Path, "/users/", "/posts")
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	postsPage, err := userPostsPage(context.Background(), r, viewer, authorID, page)
+	if errors.Is(err, errUserNotFound) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(postsPage)
+}
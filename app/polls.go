@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	minPollOptions      = 2
+	maxPollOptionLength = 100
+)
+
+var maxPollOptions int
+
+var (
+	errNoPoll      = errors.New("post has no poll")
+	errPollClosed  = errors.New("poll is closed")
+	errInvalidVote = errors.New("invalid choices")
+)
+
+// PollView is a poll as seen by the viewer. Results are left out until the viewer has voted or the poll has closed.
+type PollView struct {
+	Options  []PollOptionView `json:"options"`
+	Multiple bool             `json:"multiple"`
+	ClosesAt time.Time        `json:"closesAt"`
+	Closed   bool             `json:"closed"`
+	// Options the viewer voted for, by index
+	Choices     []int `json:"choices,omitempty"`
+	VotersCount *int  `json:"votersCount,omitempty"`
+}
+
+type PollOptionView struct {
+	Text       string `json:"text"`
+	VotesCount *int   `json:"votesCount,omitempty"`
+}
+
+// newPoll validates a poll from a request.
+func newPoll(data *CreatePollRequestBody) (*Poll, error) {
+	if len(data.Options) < minPollOptions || len(data.Options) > maxPollOptions {
+		return nil, fmt.Errorf("poll must have %d to %d options", minPollOptions, maxPollOptions)
+	}
+
+	poll := &Poll{Multiple: data.Multiple, Options: make([]PollOption, 0, len(data.Options))}
+	for _, text := range data.Options {
+		text = strings.TrimSpace(text)
+		if text == "" || len([]rune(text)) > maxPollOptionLength {
+			return nil, fmt.Errorf("poll options must be 1 to %d characters long", maxPollOptionLength)
+		}
+		poll.Options = append(poll.Options, PollOption{Text: text})
+	}
+
+	closesAt, err := time.Parse(time.RFC3339, data.ClosesAt)
+	if err != nil {
+		return nil, errors.New("closesAt must be an RFC 3339 time")
+	}
+	if !closesAt.After(time.Now()) {
+		return nil, errors.New("closesAt must be in the future")
+	}
+	poll.ClosesAt = closesAt
+
+	return poll, nil
+}
+
+// isOpen tells whether votes are still accepted. The closed flag lags behind the closing time until the job runs.
+func (p *Poll) isOpen() bool {
+	return time.Now().Before(p.ClosesAt)
+}
+
+// vote records choices of the user in the poll of the post, replacing the previous vote, and updates the counts.
+func vote(ctx context.Context, post *Post, userID primitive.ObjectID, choices []int) error {
+	if post.Poll == nil {
+		return errNoPoll
+	}
+	if !post.Poll.isOpen() {
+		return errPollClosed
+	}
+
+	choices = slices.Clone(choices)
+	slices.Sort(choices)
+	choices = slices.Compact(choices)
+	if len(choices) == 0 || (len(choices) > 1 && !post.Poll.Multiple) {
+		return errInvalidVote
+	}
+	for _, choice := range choices {
+		if choice < 0 || choice >= len(post.Poll.Options) {
+			return errInvalidVote
+		}
+	}
+
+	now := time.Now()
+	var previous PollVote
+	pollVoteCollection := mongoClient.Database(dbName).Collection(pollVotesCollectionName)
+	err := pollVoteCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"postId": post.ID, "userId": userID},
+		bson.M{
+			"$set":         bson.M{"choices": choices, "updatedAt": now},
+			"$setOnInsert": bson.M{"createdAt": now},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+	).Decode(&previous)
+	firstVote := errors.Is(err, mongo.ErrNoDocuments)
+	if err != nil && !firstVote {
+		return err
+	}
+
+	deltas := map[int]int{}
+	for _, choice := range previous.Choices {
+		deltas[choice]--
+	}
+	for _, choice := range choices {
+		deltas[choice]++
+	}
+
+	inc := bson.M{}
+	for choice, delta := range deltas {
+		if delta != 0 {
+			inc[fmt.Sprintf("poll.options.%d.votesCount", choice)] = delta
+		}
+	}
+	if firstVote {
+		inc["poll.votersCount"] = 1
+	}
+	if len(inc) == 0 {
+		return nil
+	}
+
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	_, err = postsCollection.UpdateOne(ctx, bson.M{"_id": post.ID}, bson.M{"$inc": inc})
+	return err
+}
+
+// findPollChoices returns choices of the user in polls of the given posts, keyed by post ID.
+func findPollChoices(ctx context.Context, userID primitive.ObjectID, postIDs []primitive.ObjectID) map[primitive.ObjectID][]int {
+	choices := map[primitive.ObjectID][]int{}
+	if len(postIDs) == 0 {
+		return choices
+	}
+
+	pollVoteCollection := mongoClient.Database(dbName).Collection(pollVotesCollectionName)
+	cursor, err := pollVoteCollection.Find(ctx, bson.M{"userId": userID, "postId": bson.M{"$in": postIDs}})
+	if err != nil {
+		log.Printf(">>> Failed to load poll votes: %v\n", err)
+		return choices
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var pollVote PollVote
+		if err := cursor.Decode(&pollVote); err == nil {
+			choices[pollVote.PostID] = pollVote.Choices
+		}
+	}
+
+	return choices
+}
+
+func newPollView(poll *Poll, choices []int) *PollView {
+	view := &PollView{
+		Options:  make([]PollOptionView, 0, len(poll.Options)),
+		Multiple: poll.Multiple,
+		ClosesAt: poll.ClosesAt,
+		Closed:   !poll.isOpen(),
+		Choices:  choices,
+	}
+
+	showResults := view.Closed || choices != nil
+	if showResults {
+		view.VotersCount = &poll.VotersCount
+	}
+	for i := range poll.Options {
+		option := PollOptionView{Text: poll.Options[i].Text}
+		if showResults {
+			option.VotesCount = &poll.Options[i].VotesCount
+		}
+		view.Options = append(view.Options, option)
+	}
+
+	return view
+}
+
+// closePolls notifies authors and voters of polls which have closed. Every poll is claimed by setting
+// its closed flag first, so notifications are sent once even with several replicas running the job.
+func closePolls(ctx context.Context) error {
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	for {
+		var post Post
+		err := postsCollection.FindOneAndUpdate(
+			ctx,
+			bson.M{"poll.closed": false, "poll.closesAt": bson.M{"$lte": time.Now()}},
+			bson.M{"$set": bson.M{"poll.closed": true}},
+		).Decode(&post)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := notifyPollClosed(ctx, &post); err != nil {
+			log.Printf(">>> Failed to notify about closed poll %s: %v\n", post.ID.Hex(), err)
+		}
+	}
+}
+
+func notifyPollClosed(ctx context.Context, post *Post) error {
+	err := createNotification(ctx, &Notification{
+		UserID: post.Author,
+		Type:   notificationTypePollClosed,
+		PostID: post.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	pollVoteCollection := mongoClient.Database(dbName).Collection(pollVotesCollectionName)
+	cursor, err := pollVoteCollection.Find(ctx, bson.M{"postId": post.ID})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var pollVote PollVote
+		if err := cursor.Decode(&pollVote); err != nil {
+			return err
+		}
+		if pollVote.UserID == post.Author {
+			continue
+		}
+
+		err := createNotification(ctx, &Notification{
+			UserID: pollVote.UserID,
+			Type:   notificationTypePollClosed,
+			PostID: post.ID,
+			Actor:  post.Author,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+type CreatePollRequestBody struct {
+	Options  []string `json:"options"`
+	Multiple bool     `json:"multiple"`
+	// RFC 3339 time after which votes are no longer accepted
+	ClosesAt string `json:"closesAt"`
+}
+
+type VoteRequestBody struct {
+	// Indexes of chosen options, more than one only in multiple choice polls
+	Choices []int `json:"choices"`
+}
+
+// VoteHandler godoc
+// @Summary      Vote in poll
+// @Description  Voting again replaces the previous vote, until the poll closes
+// @Tags         posts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of post with poll"
+// @Param        request   body      main.VoteRequestBody  true  "Vote"
+// @Success      200  {object}  main.PostView
+// @Router       /posts/{id}/vote [put]
+func VoteHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "/vote")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var voteData VoteRequestBody
+	err = json.NewDecoder(r.Body).Decode(&voteData)
+	if err != nil {
+		http.Error(w, "Failed to decode json", http.StatusBadRequest)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	post, err := findVisiblePost(context.Background(), viewer, postID)
+	if errors.Is(err, errPostNotFound) {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = vote(context.Background(), post, userID, voteData.Choices)
+	if errors.Is(err, errNoPoll) {
+		http.Error(w, "Post has no poll", http.StatusNotFound)
+		return
+	} else if errors.Is(err, errPollClosed) {
+		http.Error(w, "Poll is closed", http.StatusConflict)
+		return
+	} else if errors.Is(err, errInvalidVote) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	post, err = findPostByID(context.Background(), postID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildPostView(context.Background(), viewer, post))
+}
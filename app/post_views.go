@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PostView is a post as it is returned by the API, with everything it references resolved.
+type PostView struct {
+	Post
+	// Original post of a repost or a quote
+	Original *PostView `json:"original,omitempty"`
+	// Set when the post was deleted or isn't visible to the viewer, only ID is filled then
+	Tombstone bool `json:"tombstone,omitempty"`
+	// Attached images
+	Media []MediaView `json:"media,omitempty"`
+	Poll  *PollView   `json:"poll,omitempty"`
+	// Set on pinned posts heading a listing of user posts
+	Pinned bool `json:"pinned,omitempty"`
+	// Content, media and poll are left out behind the content warning
+	Collapsed bool `json:"collapsed,omitempty"`
+	// Sensitive media is left out
+	MediaHidden bool `json:"mediaHidden,omitempty"`
+}
+
+// MediaView is an attached image with signed links to it.
+type MediaView struct {
+	ID           primitive.ObjectID `json:"id"`
+	ContentType  string             `json:"contentType"`
+	Width        int                `json:"width"`
+	Height       int                `json:"height"`
+	URL          string             `json:"url"`
+	ThumbnailURL string             `json:"thumbnailUrl"`
+}
+
+// buildPostViews resolves originals of reposts and quotes, attached media and polls.
+// Deleted originals and originals hidden from the viewer are rendered as tombstones.
+func buildPostViews(ctx context.Context, viewer *Viewer, posts []Post) []PostView {
+	originalIDs := make([]primitive.ObjectID, 0)
+	mediaIDs := make([]primitive.ObjectID, 0)
+	for _, post := range posts {
+		if post.OriginalID != nil {
+			originalIDs = append(originalIDs, *post.OriginalID)
+		}
+		mediaIDs = append(mediaIDs, post.Media...)
+	}
+	originals := findPostsByIDs(ctx, originalIDs)
+	for _, original := range originals {
+		if viewer.canView(&original) {
+			mediaIDs = append(mediaIDs, original.Media...)
+		}
+	}
+	media := buildMediaViews(ctx, mediaIDs)
+
+	pollIDs := make([]primitive.ObjectID, 0)
+	for _, post := range posts {
+		if post.Poll != nil {
+			pollIDs = append(pollIDs, post.ID)
+		}
+	}
+	for _, original := range originals {
+		if original.Poll != nil {
+			pollIDs = append(pollIDs, original.ID)
+		}
+	}
+	choices := findPollChoices(ctx, viewer.ID, pollIDs)
+
+	newView := func(post Post) PostView {
+		view := PostView{Post: post, Media: make([]MediaView, 0, len(post.Media))}
+		for _, id := range post.Media {
+			if mediaView, ok := media[id]; ok {
+				view.Media = append(view.Media, mediaView)
+			}
+		}
+		if post.Poll != nil {
+			view.Poll = newPollView(post.Poll, choices[post.ID])
+		}
+		collapse(&view, viewer)
+		return view
+	}
+
+	views := make([]PostView, 0, len(posts))
+	for _, post := range posts {
+		view := newView(post)
+		if post.OriginalID != nil {
+			if original, ok := originals[*post.OriginalID]; ok && viewer.canView(&original) {
+				originalView := newView(original)
+				view.Original = &originalView
+			} else {
+				view.Original = &PostView{Post: Post{ID: *post.OriginalID}, Tombstone: true}
+			}
+		}
+		views = append(views, view)
+	}
+
+	return views
+}
+
+// collapse hides what the viewer prefers not to see right away. Authors always see their posts expanded.
+func collapse(view *PostView, viewer *Viewer) {
+	if view.Author == viewer.ID {
+		return
+	}
+
+	if view.Sensitive && len(view.Media) > 0 && !viewer.showSensitiveMedia {
+		view.Media = nil
+		view.MediaHidden = true
+	}
+
+	if view.ContentWarning != "" && !viewer.expandContentWarnings {
+		view.Content = ""
+		view.ContentHTML = ""
+		view.Tags = nil
+		view.Mentions = nil
+		view.Media = nil
+		view.Poll = nil
+		view.Preview = nil
+		view.Collapsed = true
+	}
+}
+
+func buildPostView(ctx context.Context, viewer *Viewer, post *Post) PostView {
+	return buildPostViews(ctx, viewer, []Post{*post})[0]
+}
+
+// buildPostViewsPage converts a page of posts keeping its links.
+func buildPostViewsPage(ctx context.Context, viewer *Viewer, page Page[Post]) Page[PostView] {
+	return Page[PostView]{
+		Items: buildPostViews(ctx, viewer, page.Items),
+		Next:  page.Next,
+		Prev:  page.Prev,
+	}
+}
+
+// dedupeReposts keeps only the first occurrence of every post in a feed, whether it appears as itself or reposted.
+// Quotes add their own content and are never collapsed.
+func dedupeReposts(posts []Post) []Post {
+	seen := make(map[primitive.ObjectID]bool, len(posts))
+	result := make([]Post, 0, len(posts))
+	for _, post := range posts {
+		id := post.ID
+		if post.Kind == postKindRepost && post.OriginalID != nil {
+			id = *post.OriginalID
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, post)
+	}
+	return result
+}
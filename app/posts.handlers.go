@@ -0,0 +1,587 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+var errPostNotFound = errors.New("post not found")
+
+// GetPostHandler godoc
+// @Summary      Get post
+// @Tags         posts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of post"
+// @Success      200  {object}  main.PostView
+// @Router       /posts/{id} [get]
+func GetPostHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	post, err := findVisiblePost(context.Background(), viewer, postID)
+	if errors.Is(err, errPostNotFound) {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// A single post is opened on purpose, so it's shown expanded
+	viewer.expandAll()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildPostView(context.Background(), viewer, post))
+}
+
+// UpdatePostHandler godoc
+// @Summary      Edit my post
+// @Description  Changes content, visibility or both. Previous content stays available in revisions of the post
+// @Tags         posts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of post"
+// @Param        request   body      main.UpdatePostRequestBody  true  "Update post data"
+// @Success      200  {object}  main.PostView
+// @Router       /posts/{id} [patch]
+func UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var updatePostData UpdatePostRequestBody
+	if !decodeContentRequest(w, r, &updatePostData) {
+		return
+	}
+
+	if updatePostData.Content == "" && updatePostData.Visibility == "" && updatePostData.ContentWarning == nil && updatePostData.Sensitive == nil {
+		http.Error(w, "No update fields provided", http.StatusBadRequest)
+		return
+	}
+
+	if updatePostData.Visibility != "" {
+		if _, err := parseVisibility(updatePostData.Visibility); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if updatePostData.ContentWarning != nil {
+		if _, err := parseContentWarning(*updatePostData.ContentWarning); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	post, err := findPostByID(context.Background(), postID)
+	if errors.Is(err, errPostNotFound) {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if post.Author != userID {
+		http.Error(w, "Only author can edit the post", http.StatusForbidden)
+		return
+	}
+
+	if post.Kind == postKindRepost {
+		http.Error(w, "Reposts can't be edited", http.StatusBadRequest)
+		return
+	}
+
+	if updatePostData.Sensitive != nil && *updatePostData.Sensitive != post.Sensitive && post.SensitiveLocked {
+		http.Error(w, "Sensitive flag was set by a moderator", http.StatusForbidden)
+		return
+	}
+
+	previousMentions := post.Mentions
+	if post.Visibility == visibilityPrivate {
+		// Nobody was notified about mentions in a private post
+		previousMentions = nil
+	}
+
+	visibilityNarrowed := false
+	if updatePostData.Visibility != "" {
+		visibilityNarrowed = narrowsVisibility(post.Visibility, updatePostData.Visibility)
+		post.Visibility = updatePostData.Visibility
+	}
+
+	if updatePostData.ContentWarning != nil {
+		post.ContentWarning, _ = parseContentWarning(*updatePostData.ContentWarning)
+	}
+	if updatePostData.Sensitive != nil {
+		post.Sensitive = *updatePostData.Sensitive
+	}
+
+	contentChanged := updatePostData.Content != "" && updatePostData.Content != post.Content
+	if contentChanged {
+		if err := checkContentSource(updatePostData.Content); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		post.Content = updatePostData.Content
+		post.Tags = parseTags(post.Content)
+		post.Mentions, err = resolveMentions(context.Background(), post.Author, post.Content)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		post.ContentHTML = renderContent(post.Content, post.Mentions)
+		if err := checkContentLength(post.ContentHTML); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	post.UpdatedAt = time.Now()
+
+	// TODO: wrap these updates into transaction
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	set := bson.M{
+		"content":        post.Content,
+		"tags":           post.Tags,
+		"mentions":       post.Mentions,
+		"contentHtml":    post.ContentHTML,
+		"visibility":     post.Visibility,
+		"contentWarning": post.ContentWarning,
+		"sensitive":      post.Sensitive,
+		"updatedAt":      post.UpdatedAt,
+	}
+	unset := bson.M{}
+	if previewURL := firstURL(post.Content); contentChanged && (post.Preview == nil || post.Preview.URL != previewURL) {
+		// The old card doesn't match the content anymore, the new one is attached by the job
+		post.Preview = nil
+		unset["preview"] = ""
+		unset["previewClaimedUntil"] = ""
+		if previewURL != "" {
+			set["previewUrl"] = previewURL
+		} else {
+			unset["previewUrl"] = ""
+		}
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	_, err = postsCollection.UpdateOne(context.Background(), bson.M{"_id": postID}, update)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if contentChanged {
+		err = addPostRevision(context.Background(), post, userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// A pinned post would show up on the profile of the author for a smaller audience than it was pinned for
+	if visibilityNarrowed {
+		err = unpinPost(context.Background(), post)
+		if err != nil && !errors.Is(err, errNotPinned) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = notifyMentioned(context.Background(), post, previousMentions)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildPostView(context.Background(), viewer, post))
+}
+
+// GetPostRevisionsHandler godoc
+// @Summary      Get edit history of post
+// @Description  Available to the author of the post and moderators
+// @Tags         posts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of post"
+// @Param        limit   query      int  false  "Max number of items to return"
+// @Param        before   query      string  false  "Cursor of the page with older items"
+// @Param        after   query      string  false  "Cursor of the page with newer items"
+// @Success      200  {object}  main.Page[main.PostRevision]
+// @Router       /posts/{id}/revisions [get]
+func GetPostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "/revisions")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	post, err := findPostByID(context.Background(), postID)
+	if errors.Is(err, errPostNotFound) {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if post.Author != userID {
+		moderator, err := isModerator(context.Background(), userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !moderator {
+			http.Error(w, "Only author and moderators can see revisions", http.StatusForbidden)
+			return
+		}
+	}
+
+	filter := page.filter("_id")
+	filter["postId"] = postID
+
+	var revisions []PostRevision
+	revisionCollection := mongoClient.Database(dbName).Collection(postRevisionsCollectionName)
+	cursor, err := revisionCollection.Find(context.Background(), filter, page.findOptions("_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := cursor.All(context.Background(), &revisions); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newPage(r, page, revisions, postRevisionKey))
+}
+
+// DeletePostHandler godoc
+// @Summary      Delete post
+// @Description  Available to the author of the post and moderators. Reposts and quotes of the post show a tombstone instead
+// @Tags         posts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of post"
+// @Router       /posts/{id} [delete]
+func DeletePostHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	post, err := findPostByID(context.Background(), postID)
+	if errors.Is(err, errPostNotFound) {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if post.Author != userID {
+		moderator, err := isModerator(context.Background(), userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !moderator {
+			http.Error(w, "Only author and moderators can delete the post", http.StatusForbidden)
+			return
+		}
+	}
+
+	err = deletePost(context.Background(), post)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Post deleted successfully"))
+}
+
+// decodeContentRequest decodes a JSON request carrying post or comment content, limiting its size before anything is read.
+// Writes the error response and returns false when the request can't be decoded.
+func decodeContentRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxContentRequestSize)
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			http.Error(w, errContentTooLong.Error(), http.StatusRequestEntityTooLarge)
+			return false
+		}
+		http.Error(w, "Failed to decode json", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// storePost saves a new post and distributes it to timelines.
+func storePost(ctx context.Context, post *Post) error {
+	if err := preparePost(ctx, post); err != nil {
+		return err
+	}
+	return insertPost(ctx, post)
+}
+
+// preparePost fills in defaults, resolves mentions and renders the post without writing anything,
+// so a post which is too long is rejected before anything points to it.
+func preparePost(ctx context.Context, post *Post) error {
+	if err := checkContentSource(post.Content); err != nil {
+		return err
+	}
+	if post.ReactionCounts == nil {
+		post.ReactionCounts = map[string]int{}
+	}
+	if post.Visibility == "" {
+		post.Visibility = visibilityPublic
+	}
+	post.Tags = parseTags(post.Content)
+
+	mentions, err := resolveMentions(ctx, post.Author, post.Content)
+	if err != nil {
+		return err
+	}
+	post.Mentions = mentions
+
+	post.ContentHTML = renderContent(post.Content, post.Mentions)
+	if err := checkContentLength(post.ContentHTML); err != nil {
+		return err
+	}
+	// The preview is attached later by a job, fetching pages must not slow down posting
+	post.PreviewURL = firstURL(post.Content)
+
+	return nil
+}
+
+// insertPost saves a post prepared by preparePost and distributes it to timelines.
+func insertPost(ctx context.Context, post *Post) error {
+	// TODO: add transaction
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	_, err := postsCollection.InsertOne(ctx, post)
+	if err != nil {
+		return err
+	}
+
+	return finishPost(ctx, post)
+}
+
+// finishPost does everything that follows from saving a new post. Apart from counting reposts
+// every step can run again without effect, publishDraft reruns it after an interrupted attempt.
+func finishPost(ctx context.Context, post *Post) error {
+	if post.Kind != postKindRepost {
+		// The first revision shares the ID of the post, so it's recorded only once
+		err := insertPostRevision(ctx, PostRevision{
+			ID:        post.ID,
+			PostID:    post.ID,
+			Content:   post.Content,
+			EditedBy:  post.Author,
+			CreatedAt: post.UpdatedAt,
+		})
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	_, err := userCollection.UpdateOne(ctx, bson.M{"_id": post.Author}, bson.M{"$addToSet": bson.M{"posts": post.ID}})
+	if err != nil {
+		return err
+	}
+
+	if post.OriginalID != nil {
+		err = updateRepostCounts(ctx, post, 1)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = notifyMentioned(ctx, post, nil)
+	if err != nil {
+		return err
+	}
+
+	return feedStrategy.PostCreated(ctx, post)
+}
+
+// deletePost removes the post with everything pointing to it. Reposts and quotes are kept and render a tombstone.
+func deletePost(ctx context.Context, post *Post) error {
+	// TODO: add transaction
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	_, err := postsCollection.DeleteOne(ctx, bson.M{"_id": post.ID})
+	if err != nil {
+		return err
+	}
+
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	_, err = userCollection.UpdateOne(ctx, bson.M{"_id": post.Author}, bson.M{"$pull": bson.M{"posts": post.ID, "pinnedPosts": post.ID}})
+	if err != nil {
+		return err
+	}
+
+	if post.OriginalID != nil {
+		err = updateRepostCounts(ctx, post, -1)
+		if err != nil {
+			return err
+		}
+	}
+
+	commentCollection := mongoClient.Database(dbName).Collection(commentsCollectionName)
+	_, err = commentCollection.DeleteMany(ctx, bson.M{"postId": post.ID})
+	if err != nil {
+		return err
+	}
+
+	timelineCollection := mongoClient.Database(dbName).Collection(timelinesCollectionName)
+	_, err = timelineCollection.DeleteMany(ctx, bson.M{"postId": post.ID})
+	if err != nil {
+		return err
+	}
+
+	pollVoteCollection := mongoClient.Database(dbName).Collection(pollVotesCollectionName)
+	_, err = pollVoteCollection.DeleteMany(ctx, bson.M{"postId": post.ID})
+	if err != nil {
+		return err
+	}
+
+	bookmarkCollection := mongoClient.Database(dbName).Collection(bookmarksCollectionName)
+	_, err = bookmarkCollection.DeleteMany(ctx, bson.M{"postId": post.ID})
+	if err != nil {
+		return err
+	}
+
+	reactionCollection := mongoClient.Database(dbName).Collection(reactionsCollectionName)
+	_, err = reactionCollection.DeleteMany(ctx, bson.M{"postId": post.ID})
+	if err != nil {
+		return err
+	}
+
+	notificationCollection := mongoClient.Database(dbName).Collection(notificationsCollectionName)
+	_, err = notificationCollection.DeleteMany(ctx, bson.M{"postId": post.ID})
+	if err != nil {
+		return err
+	}
+
+	revisionCollection := mongoClient.Database(dbName).Collection(postRevisionsCollectionName)
+	_, err = revisionCollection.DeleteMany(ctx, bson.M{"postId": post.ID})
+	if err != nil {
+		return err
+	}
+
+	return deleteMedia(ctx, bson.M{"postId": post.ID})
+}
+
+// updateRepostCounts changes the reposts or quotes counter of the original of the post.
+func updateRepostCounts(ctx context.Context, post *Post, delta int) error {
+	field := "repostsCount"
+	if post.Kind == postKindQuote {
+		field = "quotesCount"
+	}
+
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	_, err := postsCollection.UpdateOne(ctx, bson.M{"_id": post.OriginalID}, bson.M{"$inc": bson.M{field: delta}})
+	return err
+}
+
+func findPostByID(ctx context.Context, postID primitive.ObjectID) (*Post, error) {
+	var post Post
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	err := postsCollection.FindOne(ctx, bson.M{"_id": postID}).Decode(&post)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, errPostNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &post, nil
+}
+
+// addPostRevision records the current content of the post as its newest revision.
+func addPostRevision(ctx context.Context, post *Post, editedBy primitive.ObjectID) error {
+	return insertPostRevision(ctx, PostRevision{
+		ID:        primitive.NewObjectID(),
+		PostID:    post.ID,
+		Content:   post.Content,
+		EditedBy:  editedBy,
+		CreatedAt: post.UpdatedAt,
+	})
+}
+
+func insertPostRevision(ctx context.Context, revision PostRevision) error {
+	revisionCollection := mongoClient.Database(dbName).Collection(postRevisionsCollectionName)
+	_, err := revisionCollection.InsertOne(ctx, revision)
+	return err
+}
+
+func isModerator(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	count, err := userCollection.CountDocuments(ctx, bson.M{"_id": userID, "role": roleModerator})
+	return count > 0, err
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 500
+	maxLocationLength    = 100
+	maxProfileLinks      = 4
+	maxProfileLinkLength = 200
+)
+
+// patchField is a field of a JSON merge patch (RFC 7396): Set is false when the field is missing from the patch
+// and Null is true when it's explicitly null, which clears the field.
+type patchField[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+func (f *patchField[T]) UnmarshalJSON(data []byte) error {
+	f.Set = true
+	if string(data) == "null" {
+		f.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &f.Value)
+}
+
+// profileUpdate turns the merge patch into an update of the user document. Optional fields set to null
+// or to an empty value are removed, required fields can't be cleared. The name is changed by renameUser.
+func profileUpdate(patch UpdateProfileRequestBody) (bson.M, error) {
+	set := bson.M{}
+	unset := bson.M{}
+
+	required := []struct {
+		field string
+		value patchField[string]
+	}{
+		{"email", patch.Email},
+		{"digestSchedule", patch.DigestSchedule},
+	}
+	for _, r := range required {
+		if !r.value.Set {
+			continue
+		}
+		value := strings.TrimSpace(r.value.Value)
+		if r.value.Null || value == "" {
+			return nil, fmt.Errorf("%s can't be empty", r.field)
+		}
+		set[r.field] = value
+	}
+	if patch.Email.Set {
+		email, err := parseEmail(set["email"].(string))
+		if err != nil {
+			return nil, err
+		}
+		set["email"] = email
+	}
+	if patch.DigestSchedule.Set && !slices.Contains(digestSchedules, patch.DigestSchedule.Value) {
+		return nil, errors.New("invalid digest schedule")
+	}
+
+	for field, value := range map[string]patchField[bool]{
+		"expandContentWarnings": patch.ExpandContentWarnings,
+		"showSensitiveMedia":    patch.ShowSensitiveMedia,
+	} {
+		if value.Set {
+			// Null resets the preference to its default
+			set[field] = value.Value
+		}
+	}
+
+	optional := []struct {
+		field     string
+		value     patchField[string]
+		maxLength int
+		multiline bool
+	}{
+		{"displayName", patch.DisplayName, maxDisplayNameLength, false},
+		{"bio", patch.Bio, maxBioLength, true},
+		{"location", patch.Location, maxLocationLength, false},
+	}
+	for _, o := range optional {
+		if !o.value.Set {
+			continue
+		}
+		value, err := parseProfileText(o.field, o.value.Value, o.maxLength, o.multiline)
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			unset[o.field] = ""
+		} else {
+			set[o.field] = value
+		}
+	}
+
+	if patch.Links.Set {
+		links, err := parseProfileLinks(patch.Links.Value)
+		if err != nil {
+			return nil, err
+		}
+		if len(links) == 0 {
+			unset["links"] = ""
+		} else {
+			set["links"] = links
+		}
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update, nil
+}
+
+// parseEmail checks that s is a bare email address, a display name as in "Bob <bob@example.com>" isn't allowed.
+func parseEmail(s string) (string, error) {
+	address, err := mail.ParseAddress(s)
+	if err != nil || address.Name != "" || address.Address != s {
+		return "", errors.New("invalid email")
+	}
+	return address.Address, nil
+}
+
+// parseProfileText trims and validates a free text profile field, only the multiline ones may contain line breaks.
+func parseProfileText(field string, value string, maxLength int, multiline bool) (string, error) {
+	value = strings.TrimSpace(strings.ReplaceAll(value, "\r\n", "\n"))
+	if len([]rune(value)) > maxLength {
+		return "", fmt.Errorf("%s must be at most %d characters long", field, maxLength)
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) && !(multiline && r == '\n') {
+			return "", fmt.Errorf("%s contains invalid characters", field)
+		}
+	}
+	return value, nil
+}
+
+// parseProfileLinks validates website links of a profile, which must be absolute http or https URLs.
+func parseProfileLinks(links []string) ([]string, error) {
+	if len(links) > maxProfileLinks {
+		return nil, fmt.Errorf("at most %d links are allowed", maxProfileLinks)
+	}
+
+	result := make([]string, 0, len(links))
+	for _, link := range links {
+		link = strings.TrimSpace(link)
+		parsed, err := url.Parse(link)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid link %q, links must be http or https URLs", link)
+		}
+		if len(link) > maxProfileLinkLength {
+			return nil, fmt.Errorf("links must be at most %d characters long", maxProfileLinkLength)
+		}
+		if !slices.Contains(result, link) {
+			result = append(result, link)
+		}
+	}
+	return result, nil
+}
+
+// PublicProfile is what other users see of a user, private fields like email and preferences are left out.
+type PublicProfile struct {
+	ID             primitive.ObjectID `json:"id"`
+	Name           string             `json:"name"`
+	DisplayName    string             `json:"displayName,omitempty"`
+	Avatar         *Avatar            `json:"avatar,omitempty" swaggertype:"object,string"`
+	Bio            string             `json:"bio,omitempty"`
+	Location       string             `json:"location,omitempty"`
+	Links          []string           `json:"links,omitempty"`
+	FollowersCount int                `json:"followersCount"`
+	FollowingCount int                `json:"followingCount"`
+	PostsCount     int                `json:"postsCount"`
+	Followed       bool               `json:"followed"` // Followed by me
+	// Recent posts visible to me, the first page starts with pinned posts
+	Posts Page[PostView] `json:"posts"`
+}
+
+// findPublicProfile finds the user matching the filter and builds their profile as seen by the viewer.
+// Users the viewer can't see are reported as not found, so their existence isn't revealed.
+func findPublicProfile(ctx context.Context, r *http.Request, viewer *Viewer, filter bson.M, page pageParams) (*PublicProfile, error) {
+	var user User
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	projection := bson.M{
+		"name":           1,
+		"displayName":    1,
+		"avatar":         1,
+		"bio":            1,
+		"location":       1,
+		"links":          1,
+		"followersCount": 1,
+		"followingCount": 1,
+		"posts":          1,
+		"suspendedAt":    1,
+	}
+	err := userCollection.FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, errUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !viewer.canViewProfile(&user) {
+		return nil, errUserNotFound
+	}
+
+	posts, err := userPostsPage(ctx, r, viewer, user.ID, page)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PublicProfile{
+		ID:             user.ID,
+		Name:           user.Name,
+		DisplayName:    user.DisplayName,
+		Avatar:         user.Avatar,
+		Bio:            user.Bio,
+		Location:       user.Location,
+		Links:          user.Links,
+		FollowersCount: user.FollowersCount,
+		FollowingCount: user.FollowingCount,
+		PostsCount:     len(user.Posts),
+		Followed:       viewer.following[user.ID],
+		Posts:          posts,
+	}, nil
+}
+
+// setSuspended suspends or reinstates the user. Suspended users can't sign in, their sessions end
+// and their profiles, posts and activity are hidden from others.
+func setSuspended(ctx context.Context, userID primitive.ObjectID, suspended bool) error {
+	update := bson.M{"$unset": bson.M{"suspendedAt": ""}}
+	if suspended {
+		update = bson.M{"$set": bson.M{"suspendedAt": time.Now()}}
+	}
+
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	result, err := userCollection.UpdateOne(ctx, bson.M{"_id": userID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errUserNotFound
+	}
+
+	if suspended {
+		deleteUserSessions(userID)
+	}
+
+	return nil
+}
+
+// suspendedUserIDs returns IDs of suspended users except the given one. Few users are ever suspended,
+// so every viewer loads all of them.
+func suspendedUserIDs(ctx context.Context, exceptID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	var users []User
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	filter := bson.M{"suspendedAt": bson.M{"$exists": true}, "_id": bson.M{"$ne": exceptID}}
+	cursor, err := userCollection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
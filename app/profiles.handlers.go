@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GetUserProfileHandler godoc
+// @Summary      Get profile of user by name
+// @Description  Public part of the profile with recent posts visible to me. Former names of users redirect to their current names while reserved
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        name   path      string  true  "User name"
+// @Param        limit   query      int  false  "Max number of posts to return"
+// @Param        before   query      string  false  "Cursor of the page with older posts"
+// @Param        after   query      string  false  "Cursor of the page with newer posts"
+// @Success      200  {object}  main.PublicProfile
+// @Router       /users/{name} [get]
+func GetUserProfileHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/users/")
+	if name == "" {
+		http.Error(w, "Invalid user name", http.StatusBadRequest)
+		return
+	}
+
+	getPublicProfile(w, r, bson.M{"nameKey": usernameKey(name)}, name)
+}
+
+// GetUserProfileByIDHandler godoc
+// @Summary      Get profile of user by ID
+// @Description  Public part of the profile with recent posts visible to me
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Param        limit   query      int  false  "Max number of posts to return"
+// @Param        before   query      string  false  "Cursor of the page with older posts"
+// @Param        after   query      string  false  "Cursor of the page with newer posts"
+// @Success      200  {object}  main.PublicProfile
+// @Router       /users/id/{id} [get]
+func GetUserProfileByIDHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathObjectID(r.URL.Path, "/users/id/", "")
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	getPublicProfile(w, r, bson.M{"_id": userID}, "")
+}
+
+// getPublicProfile writes the profile of the user matching the filter as seen by the signed in user.
+// When the profile is looked up by a former name of a user, it redirects to their current name.
+func getPublicProfile(w http.ResponseWriter, r *http.Request, filter bson.M, name string) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	profile, err := findPublicProfile(context.Background(), r, viewer, filter, page)
+	if errors.Is(err, errUserNotFound) && name != "" {
+		currentName, err := renamedTo(context.Background(), viewer, name)
+		if err == nil {
+			location := url.URL{Path: "/users/" + currentName, RawQuery: r.URL.RawQuery}
+			http.Redirect(w, r, location.String(), http.StatusFound)
+			return
+		} else if !errors.Is(err, errUserNotFound) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if errors.Is(err, errUserNotFound) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// SuspendUserHandler godoc
+// @Summary      Suspend user
+// @Description  Moderators only. Suspended users are signed out, can't sign in and their profiles, posts and activity are hidden
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of user to suspend"
+// @Router       /users/{id}/suspension [put]
+func SuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	setSuspension(w, r, true)
+}
+
+// ReinstateUserHandler godoc
+// @Summary      Lift suspension of user
+// @Description  Moderators only
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of suspended user"
+// @Router       /users/{id}/suspension [delete]
+func ReinstateUserHandler(w http.ResponseWriter, r *http.Request) {
+	setSuspension(w, r, false)
+}
+
+func setSuspension(w http.ResponseWriter, r *http.Request, suspended bool) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	targetID, err := pathObjectID(r.URL.Path, "/users/", "/suspension")
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	moderator, err := isModerator(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !moderator {
+		http.Error(w, "Only moderators can suspend users", http.StatusForbidden)
+		return
+	}
+
+	if targetID == userID {
+		http.Error(w, "You can't suspend yourself", http.StatusBadRequest)
+		return
+	}
+
+	err = setSuspended(context.Background(), targetID, suspended)
+	if errors.Is(err, errUserNotFound) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if suspended {
+		w.Write([]byte("User suspended successfully"))
+	} else {
+		w.Write([]byte("User reinstated successfully"))
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestProfileUpdateEmail(t *testing.T) {
+	for _, email := range []string{"not an email", "bob@", "Bob <bob@example.com>", "a@b.com, c@d.com"} {
+		patch := UpdateProfileRequestBody{Email: patchField[string]{Set: true, Value: email}}
+		if _, err := profileUpdate(patch); err == nil {
+			t.Errorf("%q accepted", email)
+		}
+	}
+
+	patch := UpdateProfileRequestBody{Email: patchField[string]{Set: true, Value: " bob@example.com "}}
+	update, err := profileUpdate(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email := update["$set"].(bson.M)["email"]; email != "bob@example.com" {
+		t.Errorf("email: got %v", email)
+	}
+}
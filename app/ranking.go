@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	rankerLinear  = "linear"
+	rankerGravity = "gravity"
+
+	// How many newest posts of the chronological timeline are considered for ranking
+	rankingCandidatesLimit = 200
+)
+
+var (
+	rankers       []Ranker
+	rankingLogger *featureLogger
+)
+
+// RankingFeatures are the signals a Ranker scores a candidate post by.
+type RankingFeatures struct {
+	// Hours since the post was created
+	AgeHours float64 `json:"ageHours"`
+	// Reactions per hour since the post was created
+	LikesVelocity float64 `json:"likesVelocity"`
+	// How many posts of the author the viewer has reacted to
+	AuthorAffinity float64 `json:"authorAffinity"`
+	// How many users followed by the viewer have reacted to the post
+	SocialProof float64 `json:"socialProof"`
+}
+
+// Ranker scores candidate posts of the "For you" feed, higher scores go first.
+// Every viewer is assigned one of the configured rankers, which allows A/B testing them against each other.
+type Ranker interface {
+	Name() string
+	Score(features RankingFeatures) float64
+}
+
+func newRanker(name string) (Ranker, error) {
+	switch name {
+	case rankerLinear:
+		return linearRanker{recency: -0.1, likesVelocity: 1, authorAffinity: 0.5, socialProof: 2}, nil
+	case rankerGravity:
+		return gravityRanker{gravity: 1.8}, nil
+	default:
+		return nil, fmt.Errorf("unknown ranker: %s", name)
+	}
+}
+
+// newRankers parses a comma separated list of ranker names.
+func newRankers(names string) ([]Ranker, error) {
+	var result []Ranker
+	for _, name := range strings.Split(names, ",") {
+		ranker, err := newRanker(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ranker)
+	}
+	return result, nil
+}
+
+// rankerFor assigns the viewer to one of the rankers. The assignment is stable for the same viewer.
+func rankerFor(viewerID primitive.ObjectID) Ranker {
+	h := fnv.New32a()
+	h.Write(viewerID[:])
+	return rankers[h.Sum32()%uint32(len(rankers))]
+}
+
+// linearRanker is a weighted sum of features.
+type linearRanker struct {
+	recency        float64
+	likesVelocity  float64
+	authorAffinity float64
+	socialProof    float64
+}
+
+func (linearRanker) Name() string { return rankerLinear }
+
+func (r linearRanker) Score(f RankingFeatures) float64 {
+	return r.recency*f.AgeHours +
+		r.likesVelocity*f.LikesVelocity +
+		r.authorAffinity*math.Log1p(f.AuthorAffinity) +
+		r.socialProof*f.SocialProof
+}
+
+// gravityRanker divides engagement by a power of age, so that even popular posts sink over time.
+type gravityRanker struct {
+	gravity float64
+}
+
+func (gravityRanker) Name() string { return rankerGravity }
+
+func (r gravityRanker) Score(f RankingFeatures) float64 {
+	engagement := 1 + f.LikesVelocity*math.Max(f.AgeHours, 1) + f.SocialProof*2 + math.Log1p(f.AuthorAffinity)
+	return engagement / math.Pow(f.AgeHours+2, r.gravity)
+}
+
+type rankedPost struct {
+	post     Post
+	features RankingFeatures
+	score    float64
+}
+
+// rankedTimeline scores the newest posts of the viewer's timeline and returns up to limit best of them.
+func rankedTimeline(ctx context.Context, viewer *Viewer, limit int) ([]Post, error) {
+	viewerID := viewer.ID
+	candidates, err := feedStrategy.Timeline(ctx, viewer, pageParams{limit: rankingCandidatesLimit})
+	if err != nil || len(candidates) == 0 {
+		return nil, err
+	}
+	candidates = dedupeReposts(candidates)
+
+	features, err := rankingFeatures(ctx, viewerID, candidates, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	ranker := rankerFor(viewerID)
+	ranked := make([]rankedPost, 0, len(candidates))
+	for i, post := range candidates {
+		ranked = append(ranked, rankedPost{post: post, features: features[i], score: ranker.Score(features[i])})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	requestID := primitive.NewObjectID()
+	posts := make([]Post, 0, len(ranked))
+	for position, rp := range ranked {
+		rankingLogger.log(rankingLogEntry{
+			RequestID: requestID,
+			Time:      time.Now(),
+			ViewerID:  viewerID,
+			Ranker:    ranker.Name(),
+			PostID:    rp.post.ID,
+			Position:  position,
+			Score:     rp.score,
+			Features:  rp.features,
+		})
+		posts = append(posts, rp.post)
+	}
+
+	return posts, nil
+}
+
+// rankingFeatures computes features of posts, in the same order, from the viewer's perspective.
+func rankingFeatures(ctx context.Context, viewerID primitive.ObjectID, posts []Post, now time.Time) ([]RankingFeatures, error) {
+	affinity, err := authorAffinity(ctx, viewerID, posts)
+	if err != nil {
+		return nil, err
+	}
+
+	socialProof, err := socialProof(ctx, viewerID, posts)
+	if err != nil {
+		return nil, err
+	}
+
+	features := make([]RankingFeatures, 0, len(posts))
+	for _, post := range posts {
+		ageHours := math.Max(now.Sub(post.CreatedAt).Hours(), 0)
+		features = append(features, RankingFeatures{
+			AgeHours:       ageHours,
+			LikesVelocity:  float64(post.reactionsCount()) / math.Max(ageHours, 1),
+			AuthorAffinity: float64(affinity[post.Author]),
+			SocialProof:    float64(socialProof[post.ID]),
+		})
+	}
+
+	return features, nil
+}
+
+// authorAffinity counts reactions of the viewer to posts per author of the given posts.
+func authorAffinity(ctx context.Context, viewerID primitive.ObjectID, posts []Post) (map[primitive.ObjectID]int, error) {
+	authors := make([]primitive.ObjectID, 0, len(posts))
+	for _, post := range posts {
+		authors = append(authors, post.Author)
+	}
+
+	return countReactions(ctx, bson.M{"userId": viewerID, "postAuthor": bson.M{"$in": authors}}, "$postAuthor")
+}
+
+// socialProof counts, per post, users followed by the viewer who have reacted to it.
+func socialProof(ctx context.Context, viewerID primitive.ObjectID, posts []Post) (map[primitive.ObjectID]int, error) {
+	followees, err := followeeIDs(ctx, viewerID)
+	if err != nil || len(followees) == 0 {
+		return map[primitive.ObjectID]int{}, err
+	}
+
+	postIDs := make([]primitive.ObjectID, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+
+	return countReactions(ctx, bson.M{"postId": bson.M{"$in": postIDs}, "userId": bson.M{"$in": followees}}, "$postId")
+}
+
+// countReactions counts reactions matching the filter grouped by the given field.
+func countReactions(ctx context.Context, filter bson.M, groupBy string) (map[primitive.ObjectID]int, error) {
+	reactionCollection := mongoClient.Database(dbName).Collection(reactionsCollectionName)
+	cursor, err := reactionCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{"_id": groupBy, "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int                `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[primitive.ObjectID]int, len(groups))
+	for _, group := range groups {
+		counts[group.ID] = group.Count
+	}
+
+	return counts, nil
+}
+
+// rankingLogEntry is one line of the ranking log, used to analyze rankers offline.
+type rankingLogEntry struct {
+	RequestID primitive.ObjectID `json:"requestId"`
+	Time      time.Time          `json:"time"`
+	ViewerID  primitive.ObjectID `json:"viewerId"`
+	Ranker    string             `json:"ranker"`
+	PostID    primitive.ObjectID `json:"postId"`
+	Position  int                `json:"position"`
+	Score     float64            `json:"score"`
+	Features  RankingFeatures    `json:"features"`
+}
+
+// featureLogger appends ranking log entries as JSON lines.
+type featureLogger struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// newFeatureLogger opens the log file for appending. Logging is disabled when path is empty.
+func newFeatureLogger(path string) (*featureLogger, error) {
+	if path == "" {
+		return &featureLogger{encoder: json.NewEncoder(io.Discard)}, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &featureLogger{encoder: json.NewEncoder(file)}, nil
+}
+
+func (l *featureLogger) log(entry rankingLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.encoder.Encode(entry)
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultReaction is what a like is. Likes made before reactions existed were migrated to it.
+const defaultReaction = "like"
+
+var reactionTypes []string
+
+type ReactRequestBody struct {
+	Type string `json:"type"`
+}
+
+// UserReaction is an entry of the list of users who reacted to a post.
+type UserReaction struct {
+	User      UserSummary `json:"user"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"createdAt"`
+}
+
+// parseReactionTypes parses a comma separated list of allowed reactions, which must include the default one.
+func parseReactionTypes(types string) ([]string, error) {
+	var result []string
+	for _, reactionType := range strings.Split(types, ",") {
+		reactionType = strings.TrimSpace(reactionType)
+		if reactionType != "" && !slices.Contains(result, reactionType) {
+			result = append(result, reactionType)
+		}
+	}
+
+	if !slices.Contains(result, defaultReaction) {
+		return nil, fmt.Errorf("reactions must include %s", defaultReaction)
+	}
+
+	return result, nil
+}
+
+// ReactToPostHandler godoc
+// @Summary      React to post
+// @Description  One reaction per user per post, reacting again switches the reaction
+// @Tags         reactions
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of post"
+// @Param        request   body      main.ReactRequestBody  true  "Reaction"
+// @Success      200  {object}  main.Reaction
+// @Router       /posts/{id}/reaction [put]
+func ReactToPostHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "/reaction")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var reactData ReactRequestBody
+	err = json.NewDecoder(r.Body).Decode(&reactData)
+	if err != nil {
+		http.Error(w, "Failed to decode json", http.StatusBadRequest)
+		return
+	}
+
+	if !slices.Contains(reactionTypes, reactData.Type) {
+		http.Error(w, "Unknown reaction", http.StatusBadRequest)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	post, err := findVisiblePost(context.Background(), viewer, postID)
+	if errors.Is(err, errPostNotFound) {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	reaction, _, err := react(context.Background(), post, userID, reactData.Type)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reaction)
+}
+
+// RemoveReactionHandler godoc
+// @Summary      Remove my reaction from post
+// @Tags         reactions
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of post"
+// @Router       /posts/{id}/reaction [delete]
+func RemoveReactionHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "/reaction")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var reaction Reaction
+	reactionCollection := mongoClient.Database(dbName).Collection(reactionsCollectionName)
+	err = reactionCollection.FindOneAndDelete(context.Background(), bson.M{"postId": postID, "userId": userID}).Decode(&reaction)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, "You haven't reacted to the post", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = updateReactionCounts(context.Background(), postID, reactionCountsDelta(reaction.Type, -1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Reaction removed successfully"))
+}
+
+// GetReactionsHandler godoc
+// @Summary      Get users who reacted to post
+// @Tags         reactions
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of post"
+// @Param        type   query      string  false  "Only users who reacted with this reaction"
+// @Param        limit   query      int  false  "Max number of items to return"
+// @Param        before   query      string  false  "Cursor of the page with older items"
+// @Param        after   query      string  false  "Cursor of the page with newer items"
+// @Success      200  {object}  main.Page[main.UserReaction]
+// @Router       /posts/{id}/reactions [get]
+func GetReactionsHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "/reactions")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = findVisiblePost(context.Background(), viewer, postID)
+	if errors.Is(err, errPostNotFound) {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := page.filter("_id")
+	filter["postId"] = postID
+	if reactionType := r.URL.Query().Get("type"); reactionType != "" {
+		filter["type"] = reactionType
+	}
+	viewer.restrictUsers(filter, "userId")
+
+	var reactions []Reaction
+	reactionCollection := mongoClient.Database(dbName).Collection(reactionsCollectionName)
+	cursor, err := reactionCollection.Find(context.Background(), filter, page.findOptions("_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := cursor.All(context.Background(), &reactions); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	reactionsPage := newPage(r, page, reactions, reactionKey)
+
+	userIDs := make([]primitive.ObjectID, 0, len(reactionsPage.Items))
+	for _, reaction := range reactionsPage.Items {
+		userIDs = append(userIDs, reaction.UserID)
+	}
+
+	users, err := findUserSummaries(context.Background(), userIDs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	usersByID := make(map[primitive.ObjectID]UserSummary, len(users))
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
+
+	userReactions := make([]UserReaction, 0, len(reactionsPage.Items))
+	for _, reaction := range reactionsPage.Items {
+		if user, ok := usersByID[reaction.UserID]; ok {
+			userReactions = append(userReactions, UserReaction{User: user, Type: reaction.Type, CreatedAt: reaction.CreatedAt})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Page[UserReaction]{Items: userReactions, Next: reactionsPage.Next, Prev: reactionsPage.Prev})
+}
+
+// react sets the reaction of the user to the post, replacing the previous one.
+// Returns the previous reaction type, empty when the user hasn't reacted before.
+func react(ctx context.Context, post *Post, userID primitive.ObjectID, reactionType string) (*Reaction, string, error) {
+	reaction := &Reaction{
+		ID:         primitive.NewObjectID(),
+		PostID:     post.ID,
+		PostAuthor: post.Author,
+		UserID:     userID,
+		Type:       reactionType,
+		CreatedAt:  time.Now(),
+	}
+
+	var previous Reaction
+	reactionCollection := mongoClient.Database(dbName).Collection(reactionsCollectionName)
+	err := reactionCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"postId": post.ID, "userId": userID},
+		bson.M{
+			"$set":         bson.M{"type": reactionType, "createdAt": reaction.CreatedAt},
+			"$setOnInsert": bson.M{"_id": reaction.ID, "postAuthor": post.Author},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+	).Decode(&previous)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		previous = Reaction{}
+	} else if err != nil {
+		return nil, "", err
+	} else {
+		reaction.ID = previous.ID
+	}
+
+	if previous.Type == reactionType {
+		return reaction, previous.Type, nil
+	}
+
+	delta := reactionCountsDelta(reactionType, 1)
+	if previous.Type != "" {
+		for field, value := range reactionCountsDelta(previous.Type, -1) {
+			delta[field] = value
+		}
+	}
+	err = updateReactionCounts(ctx, post.ID, delta)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Switching the reaction isn't worth another notification
+	if previous.Type == "" && post.Author != userID {
+		notification := &Notification{
+			UserID:   post.Author,
+			Type:     notificationTypeReaction,
+			PostID:   post.ID,
+			Actor:    userID,
+			Reaction: reactionType,
+		}
+		if reactionType == defaultReaction {
+			notification.Type = notificationTypeLike
+			notification.LikedBy = userID
+		}
+		err = createNotification(ctx, notification)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	return reaction, previous.Type, nil
+}
+
+// reactionCountsDelta returns $inc fields changing counters of the reaction. Likes are also counted in likesCount.
+func reactionCountsDelta(reactionType string, delta int) bson.M {
+	fields := bson.M{"reactionCounts." + reactionType: delta}
+	if reactionType == defaultReaction {
+		fields["likesCount"] = delta
+	}
+	return fields
+}
+
+func updateReactionCounts(ctx context.Context, postID primitive.ObjectID, delta bson.M) error {
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	_, err := postsCollection.UpdateOne(ctx, bson.M{"_id": postID}, bson.M{"$inc": delta})
+	return err
+}
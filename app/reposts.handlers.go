@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+type QuotePostRequestBody struct {
+	Content    string `json:"content"`
+	Visibility string `json:"visibility" enums:"public,followers,mentioned,private" default:"public"`
+}
+
+// RepostHandler godoc
+// @Summary      Repost post
+// @Description  Reposting a repost reposts its original. Only public posts can be reposted
+// @Tags         posts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of post to repost"
+// @Success      201  {object}  main.PostView
+// @Router       /posts/{id}/repost [post]
+func RepostHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "/repost")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	original, err := findOriginalPost(context.Background(), viewer, postID)
+	if errors.Is(err, errPostNotFound) {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Sharing must not widen the audience of the original
+	if original.Visibility != visibilityPublic {
+		http.Error(w, "Only public posts can be shared", http.StatusForbidden)
+		return
+	}
+
+	now := time.Now()
+	repost := &Post{
+		ID:         primitive.NewObjectID(),
+		Kind:       postKindRepost,
+		Visibility: visibilityPublic,
+		Author:     userID,
+		OriginalID: &original.ID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	err = storePost(context.Background(), repost)
+	if mongo.IsDuplicateKeyError(err) {
+		http.Error(w, "Post is already reposted by you", http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(buildPostView(context.Background(), viewer, repost))
+}
+
+// UndoRepostHandler godoc
+// @Summary      Undo repost
+// @Tags         posts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of reposted post"
+// @Router       /posts/{id}/repost [delete]
+func UndoRepostHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "/repost")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var repost Post
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	filter := bson.M{"kind": postKindRepost, "originalId": postID, "author": userID}
+	err = postsCollection.FindOne(context.Background(), filter).Decode(&repost)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, "Post is not reposted by you", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = deletePost(context.Background(), &repost)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Repost removed successfully"))
+}
+
+// QuotePostHandler godoc
+// @Summary      Quote post
+// @Description  Creates a new post with own content referencing the quoted one. Only public posts can be quoted
+// @Tags         posts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID of post to quote"
+// @Param        request   body      main.QuotePostRequestBody  true  "Quote data"
+// @Success      201  {object}  main.PostView
+// @Router       /posts/{id}/quote [post]
+func QuotePostHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	postID, err := pathObjectID(r.URL.Path, "/posts/", "/quote")
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var quotePostData QuotePostRequestBody
+	if !decodeContentRequest(w, r, &quotePostData) {
+		return
+	}
+
+	if quotePostData.Content == "" {
+		http.Error(w, "Content is required", http.StatusBadRequest)
+		return
+	}
+
+	visibility, err := parseVisibility(quotePostData.Visibility)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	original, err := findOriginalPost(context.Background(), viewer, postID)
+	if errors.Is(err, errPostNotFound) {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Sharing must not widen the audience of the original
+	if original.Visibility != visibilityPublic {
+		http.Error(w, "Only public posts can be shared", http.StatusForbidden)
+		return
+	}
+
+	now := time.Now()
+	quote := &Post{
+		ID:         primitive.NewObjectID(),
+		Kind:       postKindQuote,
+		Visibility: visibility,
+		Author:     userID,
+		Content:    quotePostData.Content,
+		OriginalID: &original.ID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	err = storePost(context.Background(), quote)
+	if errors.Is(err, errContentTooLong) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(buildPostView(context.Background(), viewer, quote))
+}
+
+// findOriginalPost returns the post, or the original of it when the post is a repost. Both must be visible to the viewer.
+func findOriginalPost(ctx context.Context, viewer *Viewer, postID primitive.ObjectID) (*Post, error) {
+	post, err := findVisiblePost(ctx, viewer, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.Kind == postKindRepost && post.OriginalID != nil {
+		return findVisiblePost(ctx, viewer, *post.OriginalID)
+	}
+
+	return post, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	maxTagLength = 64
+
+	// Uses of a tag are weighted by exp(-age/window), recent uses are compared to the long term trend
+	trendingShortWindow = time.Hour
+	trendingLongWindow  = 24 * time.Hour
+	// Only uses within this period are considered at all
+	trendingLookback = 7 * 24 * time.Hour
+	// Keeps rare tags from jumping to the top after a couple of uses
+	trendingSmoothing = 3.0
+	trendingMinUses   = 2.0
+	trendingMaxTags   = 50
+
+	trendingDocumentID = "current"
+)
+
+var tagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])#([\p{L}\p{N}_]+)`)
+
+// TrendingTag is a tag whose use is rising faster than its long term trend.
+type TrendingTag struct {
+	Tag string `bson:"tag" json:"tag"`
+	// How much faster the tag is used recently than usual
+	Score float64 `bson:"score" json:"score"`
+	// Decayed number of recent uses
+	RecentUses float64 `bson:"recentUses" json:"recentUses"`
+}
+
+type trendingTags struct {
+	ID         string        `bson:"_id"`
+	Tags       []TrendingTag `bson:"tags"`
+	ComputedAt time.Time     `bson:"computedAt"`
+}
+
+// parseTags extracts unique normalized #tags from content in order of appearance.
+func parseTags(content string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, match := range tagPattern.FindAllStringSubmatch(content, -1) {
+		tag := normalizeTag(match[1])
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// normalizeTag lowercases the tag. Tags without letters (like #1) and too long tags are rejected with an empty result.
+func normalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	if len([]rune(tag)) > maxTagLength || strings.IndexFunc(tag, unicode.IsLetter) < 0 {
+		return ""
+	}
+	return tag
+}
+
+// refreshTrendingTags recomputes trending tags from posts of the lookback period.
+func refreshTrendingTags(ctx context.Context) error {
+	now := time.Now()
+	decayed := func(window time.Duration) bson.M {
+		return bson.M{"$sum": bson.M{"$exp": bson.M{"$divide": bson.A{
+			bson.M{"$subtract": bson.A{"$createdAt", now}},
+			window.Milliseconds(),
+		}}}}
+	}
+
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	cursor, err := postsCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"createdAt": bson.M{"$gte": now.Add(-trendingLookback)},
+			"tags.0":    bson.M{"$exists": true},
+			// Trends are shown to everyone, so only public posts count
+			"visibility": visibilityPublic,
+		}}},
+		{{Key: "$project", Value: bson.M{"tags": 1, "createdAt": 1}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$tags",
+			"short": decayed(trendingShortWindow),
+			"long":  decayed(trendingLongWindow),
+		}}},
+		{{Key: "$match", Value: bson.M{"short": bson.M{"$gte": trendingMinUses}}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var groups []struct {
+		Tag   string  `bson:"_id"`
+		Short float64 `bson:"short"`
+		Long  float64 `bson:"long"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return err
+	}
+
+	// With a steady rate of use, the short window would get this share of the long window's uses
+	expectedShare := trendingShortWindow.Hours() / trendingLongWindow.Hours()
+
+	tags := make([]TrendingTag, 0, len(groups))
+	for _, group := range groups {
+		expected := group.Long * expectedShare
+		tags = append(tags, TrendingTag{
+			Tag:        group.Tag,
+			Score:      math.Round(group.Short/(expected+trendingSmoothing)*1000) / 1000,
+			RecentUses: math.Round(group.Short*100) / 100,
+		})
+	}
+	slices.SortFunc(tags, func(a, b TrendingTag) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	if len(tags) > trendingMaxTags {
+		tags = tags[:trendingMaxTags]
+	}
+
+	trendingCollection := mongoClient.Database(dbName).Collection(trendingTagsCollectionName)
+	_, err = trendingCollection.ReplaceOne(
+		ctx,
+		bson.M{"_id": trendingDocumentID},
+		trendingTags{ID: trendingDocumentID, Tags: tags, ComputedAt: now},
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
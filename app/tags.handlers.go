@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const defaultTrendingTagsLimit = 10
+
+// GetTagPostsHandler godoc
+// @Summary      Get posts with tag
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        tag   path      string  true  "Tag, with or without #"
+// @Param        limit   query      int  false  "Max number of items to return"
+// @Param        before   query      string  false  "Cursor of the page with older items"
+// @Param        after   query      string  false  "Cursor of the page with newer items"
+// @Success      200  {object}  main.Page[main.PostView]
+// @Router       /tags/{tag}/posts [get]
+func GetTagPostsHandler(w http.ResponseWriter, r *http.Request) {
+	userContextData := r.Context().Value(userContextKey).(*UserContextData)
+	if userContextData == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	userID := userContextData.ID
+
+	if !strings.HasSuffix(r.URL.Path, "/posts") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	tagFromPath := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/tags/"), "/posts")
+	tagFromPath, err := url.PathUnescape(tagFromPath)
+	if err != nil {
+		http.Error(w, "Invalid tag", http.StatusBadRequest)
+		return
+	}
+
+	tag := normalizeTag(tagFromPath)
+	if tag == "" {
+		http.Error(w, "Invalid tag", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	viewer, err := loadViewer(context.Background(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	filter := page.filter("_id")
+	filter["tags"] = tag
+	viewer.restrictFeed(filter)
+
+	var posts []Post
+	postsCollection := mongoClient.Database(dbName).Collection(postsCollectionName)
+	cursor, err := postsCollection.Find(context.Background(), filter, page.findOptions("_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := cursor.All(context.Background(), &posts); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildPostViewsPage(context.Background(), viewer, newPage(r, page, posts, postKey)))
+}
+
+// GetTrendingTagsHandler godoc
+// @Summary      Get trending tags
+// @Description  Tags whose use is rising the fastest, refreshed periodically
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        limit   query      int  false  "Max number of tags to return"
+// @Success      200  {array}  main.TrendingTag
+// @Router       /tags/trending [get]
+func GetTrendingTagsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := defaultTrendingTagsLimit
+	if limitFromQuery := r.URL.Query().Get("limit"); limitFromQuery != "" {
+		var err error
+		limit, err = strconv.Atoi(limitFromQuery)
+		if err != nil || limit <= 0 || limit > trendingMaxTags {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var trending trendingTags
+	trendingCollection := mongoClient.Database(dbName).Collection(trendingTagsCollectionName)
+	err := trendingCollection.FindOne(context.Background(), bson.M{"_id": trendingDocumentID}).Decode(&trending)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tags := trending.Tags
+	if tags == nil {
+		tags = []TrendingTag{}
+	}
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
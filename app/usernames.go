@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const maxUsernameLength = 30
+
+var (
+	// How often a user can change their name
+	usernameChangeInterval time.Duration
+	// How long a former name stays reserved for its user and redirects to the new one
+	usernameReservation time.Duration
+)
+
+var (
+	errNameTaken     = errors.New("name is already taken")
+	errRenameTooSoon = errors.New("name was changed too recently")
+	// Another request changed the name since it was read
+	errRenameConflict = errors.New("name was changed by another request")
+)
+
+// Names are mentioned and used in profile URLs, so they consist of the same characters as mentions
+var usernamePattern = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+
+// ReservedName is a former name of a user. Nobody else can take it until it expires.
+type ReservedName struct {
+	Key       string             `bson:"_id"` // usernameKey of the name
+	UserID    primitive.ObjectID `bson:"userId"`
+	ExpiresAt time.Time          `bson:"expiresAt"`
+}
+
+// parseUsername validates a user name from a request.
+func parseUsername(name string) (string, error) {
+	if len([]rune(name)) > maxUsernameLength || !usernamePattern.MatchString(name) {
+		return "", fmt.Errorf("name must be 1 to %d letters, digits or underscores", maxUsernameLength)
+	}
+	return name, nil
+}
+
+// usernameKey is what makes names unique: Alice and alice are the same name, written differently.
+// Lookups by name, including mentions, match the key.
+func usernameKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// checkNameAvailable tells whether the name can be taken by the user: it's neither the name of another user
+// nor reserved for one. Pass primitive.NilObjectID for users yet to be created.
+func checkNameAvailable(ctx context.Context, name string, userID primitive.ObjectID) error {
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	count, err := userCollection.CountDocuments(ctx, bson.M{"nameKey": usernameKey(name), "_id": bson.M{"$ne": userID}})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errNameTaken
+	}
+
+	reservedNameCollection := mongoClient.Database(dbName).Collection(reservedNamesCollectionName)
+	count, err = reservedNameCollection.CountDocuments(ctx, bson.M{
+		"_id":       usernameKey(name),
+		"userId":    bson.M{"$ne": userID},
+		"expiresAt": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errNameTaken
+	}
+
+	return nil
+}
+
+// renameUser changes the name of the user, at most once per usernameChangeInterval. The former name is reserved
+// before it's released, so it's never free for others, and the user can take it back while it's reserved.
+func renameUser(ctx context.Context, userID primitive.ObjectID, name string) error {
+	var user User
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	projection := bson.M{"name": 1, "nameChangedAt": 1}
+	err := userCollection.FindOne(ctx, bson.M{"_id": userID}, options.FindOne().SetProjection(projection)).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return errUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.Name == name {
+		return nil
+	}
+
+	now := time.Now()
+	if user.NameChangedAt != nil && now.Before(user.NameChangedAt.Add(usernameChangeInterval)) {
+		return fmt.Errorf("%w, it can be changed again after %s",
+			errRenameTooSoon, user.NameChangedAt.Add(usernameChangeInterval).Format(time.RFC3339))
+	}
+
+	// Changing only the case of letters keeps the name, there's nothing to reserve
+	formerKey := usernameKey(user.Name)
+	reserved := formerKey != usernameKey(name)
+	if reserved {
+		if err := reserveName(ctx, formerKey, userID, now.Add(usernameReservation)); err != nil {
+			return err
+		}
+	}
+
+	err = changeName(ctx, userID, user.Name, name, now)
+	if err != nil {
+		if reserved {
+			reservedNameCollection := mongoClient.Database(dbName).Collection(reservedNamesCollectionName)
+			if _, rollbackErr := reservedNameCollection.DeleteOne(ctx, bson.M{"_id": formerKey, "userId": userID}); rollbackErr != nil {
+				log.Printf(">>> Failed to release name %s reserved for %s: %v\n", formerKey, userID.Hex(), rollbackErr)
+			}
+		}
+		return err
+	}
+
+	renameSessions(userID, name)
+	return nil
+}
+
+// reserveName reserves the name for the user unless it's reserved for someone else.
+func reserveName(ctx context.Context, key string, userID primitive.ObjectID, expiresAt time.Time) error {
+	reservedNameCollection := mongoClient.Database(dbName).Collection(reservedNamesCollectionName)
+	_, err := reservedNameCollection.UpdateOne(
+		ctx,
+		bson.M{"_id": key, "$or": bson.A{bson.M{"userId": userID}, bson.M{"expiresAt": bson.M{"$lte": time.Now()}}}},
+		bson.M{"$set": bson.M{"userId": userID, "expiresAt": expiresAt}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return errNameTaken
+	}
+	return err
+}
+
+// changeName replaces the former name of the user, which has to be reserved already.
+func changeName(ctx context.Context, userID primitive.ObjectID, former string, name string, now time.Time) error {
+	if err := checkNameAvailable(ctx, name, userID); err != nil {
+		return err
+	}
+
+	// The former name in the filter makes concurrent renames of the user fail instead of overwriting each other
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	result, err := userCollection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID, "name": former},
+		bson.M{"$set": bson.M{"name": name, "nameKey": usernameKey(name), "nameChangedAt": now}},
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return errNameTaken
+	}
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errRenameConflict
+	}
+
+	// A former name taken back isn't reserved anymore
+	reservedNameCollection := mongoClient.Database(dbName).Collection(reservedNamesCollectionName)
+	_, err = reservedNameCollection.DeleteOne(ctx, bson.M{"_id": usernameKey(name), "userId": userID})
+	return err
+}
+
+// renamedTo returns the current name of the user who used to have the name, when it's still reserved
+// and the viewer can see the user. Otherwise it returns errUserNotFound.
+func renamedTo(ctx context.Context, viewer *Viewer, name string) (string, error) {
+	var reserved ReservedName
+	reservedNameCollection := mongoClient.Database(dbName).Collection(reservedNamesCollectionName)
+	err := reservedNameCollection.FindOne(ctx, bson.M{"_id": usernameKey(name), "expiresAt": bson.M{"$gt": time.Now()}}).Decode(&reserved)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", errUserNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var user User
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	projection := bson.M{"name": 1, "suspendedAt": 1}
+	err = userCollection.FindOne(ctx, bson.M{"_id": reserved.UserID}, options.FindOne().SetProjection(projection)).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", errUserNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if !viewer.canViewProfile(&user) {
+		return "", errUserNotFound
+	}
+	return user.Name, nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"slices"
+)
+
+const (
+	visibilityPublic = "public"
+	// Followers of the author and mentioned users
+	visibilityFollowers = "followers"
+	// Mentioned users only
+	visibilityMentioned = "mentioned"
+	// The author only
+	visibilityPrivate = "private"
+)
+
+var visibilities = []string{visibilityPublic, visibilityFollowers, visibilityMentioned, visibilityPrivate}
+
+// Visibility levels from the narrowest audience to the widest
+var visibilityWidth = map[string]int{visibilityPrivate: 0, visibilityMentioned: 1, visibilityFollowers: 2, visibilityPublic: 3}
+
+var errInvalidVisibility = errors.New("visibility must be one of public, followers, mentioned, private")
+
+// Viewer is the user on whose behalf posts are read. Every read path decides what to return through it:
+// canView for posts already loaded and restrictPosts for queries, restrictFeed for feeds.
+// Interactions with users, like following them, are checked through it too.
+type Viewer struct {
+	ID        primitive.ObjectID
+	followees []primitive.ObjectID
+	following map[primitive.ObjectID]bool
+	// Users blocked by the viewer or blocking them, posts are hidden both ways
+	blockedIDs []primitive.ObjectID
+	blocked    map[primitive.ObjectID]bool
+	blockedBy  map[primitive.ObjectID]bool
+	// Users muted by the viewer, hidden from feeds and notifications only
+	mutedIDs []primitive.ObjectID
+	muted    map[primitive.ObjectID]bool
+	// Suspended users other than the viewer, hidden like blocked ones
+	suspendedIDs []primitive.ObjectID
+	suspended    map[primitive.ObjectID]bool
+	// Preferences of the user, post views collapse content warnings and sensitive media unless set
+	expandContentWarnings bool
+	showSensitiveMedia    bool
+}
+
+func loadViewer(ctx context.Context, userID primitive.ObjectID) (*Viewer, error) {
+	followees, err := followeeIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	following := idSet(followees)
+
+	blocking, blockedBy, muting, err := userRelations(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	blockedIDs := append(blocking, blockedBy...)
+
+	suspendedIDs, err := suspendedUserIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var user User
+	userCollection := mongoClient.Database(dbName).Collection(usersCollectionName)
+	projection := bson.M{"expandContentWarnings": 1, "showSensitiveMedia": 1}
+	err = userCollection.FindOne(ctx, bson.M{"_id": userID}, options.FindOne().SetProjection(projection)).Decode(&user)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	return &Viewer{
+		ID:                    userID,
+		followees:             followees,
+		following:             following,
+		blockedIDs:            blockedIDs,
+		blocked:               idSet(blockedIDs),
+		blockedBy:             idSet(blockedBy),
+		mutedIDs:              muting,
+		muted:                 idSet(muting),
+		suspendedIDs:          suspendedIDs,
+		suspended:             idSet(suspendedIDs),
+		expandContentWarnings: user.ExpandContentWarnings,
+		showSensitiveMedia:    user.ShowSensitiveMedia,
+	}, nil
+}
+
+// expandAll makes post views of the viewer show everything regardless of preferences,
+// for when the user explicitly opened a post.
+func (v *Viewer) expandAll() {
+	v.expandContentWarnings = true
+	v.showSensitiveMedia = true
+}
+
+func idSet(ids []primitive.ObjectID) map[primitive.ObjectID]bool {
+	set := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// canView tells whether the post is visible to the viewer. Authors always see their posts.
+// Posts the viewer can't see can't be interacted with either, as handlers load them through findVisiblePost.
+func (v *Viewer) canView(post *Post) bool {
+	if post.Author == v.ID {
+		return true
+	}
+	if v.blocked[post.Author] || v.suspended[post.Author] {
+		return false
+	}
+
+	switch post.Visibility {
+	case visibilityPublic:
+		return true
+	case visibilityFollowers:
+		return v.following[post.Author] || v.isMentioned(post)
+	case visibilityMentioned:
+		return v.isMentioned(post)
+	default:
+		return false
+	}
+}
+
+// canViewProfile tells whether the user's profile and posts are shown to the viewer.
+// Suspended users are hidden from everyone but themselves and blockers from users they blocked.
+func (v *Viewer) canViewProfile(user *User) bool {
+	return user.ID == v.ID || (user.SuspendedAt == nil && !v.blockedBy[user.ID])
+}
+
+// canSee tells whether activity of the user, like notifications, is shown to the viewer: the user is neither
+// blocked, muted nor suspended.
+func (v *Viewer) canSee(userID primitive.ObjectID) bool {
+	return !v.blocked[userID] && !v.muted[userID] && !v.suspended[userID]
+}
+
+// canFollow tells whether the viewer may follow the user: neither of them blocked the other and the user
+// isn't suspended.
+func (v *Viewer) canFollow(userID primitive.ObjectID) bool {
+	return userID != v.ID && !v.blocked[userID] && !v.suspended[userID]
+}
+
+func (v *Viewer) isMentioned(post *Post) bool {
+	for _, mention := range post.Mentions {
+		if mention.UserID == v.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictPosts narrows a filter of the posts collection to posts the viewer can see, same as canView.
+func (v *Viewer) restrictPosts(filter bson.M) {
+	visible := bson.M{"$or": bson.A{
+		bson.M{"visibility": visibilityPublic},
+		bson.M{"author": v.ID},
+		bson.M{"visibility": bson.M{"$in": bson.A{visibilityFollowers, visibilityMentioned}}, "mentions.userId": v.ID},
+		bson.M{"visibility": visibilityFollowers, "author": bson.M{"$in": v.followees}},
+	}}
+
+	conditions, _ := filter["$and"].(bson.A)
+	filter["$and"] = append(conditions, visible)
+	v.restrictUsers(filter, "author")
+}
+
+// restrictUsers narrows a filter to documents whose field isn't a blocked or suspended user, same as canSee
+// without muting. Comments and reactions are filtered by their author this way.
+func (v *Viewer) restrictUsers(filter bson.M, field string) {
+	hidden := slices.Concat(v.blockedIDs, v.suspendedIDs)
+	if len(hidden) == 0 {
+		return
+	}
+	conditions, _ := filter["$and"].(bson.A)
+	filter["$and"] = append(conditions, bson.M{field: bson.M{"$nin": hidden}})
+}
+
+// restrictFeed narrows a filter of the posts collection for feeds: like restrictPosts, and posts of muted users
+// are left out too. Muted users' posts are still shown when the viewer opens them or their profile.
+func (v *Viewer) restrictFeed(filter bson.M) {
+	v.restrictPosts(filter)
+	if len(v.mutedIDs) > 0 {
+		filter["$and"] = append(filter["$and"].(bson.A), bson.M{"author": bson.M{"$nin": v.mutedIDs}})
+	}
+}
+
+// visiblePosts keeps only posts the viewer can see, preserving the order.
+func (v *Viewer) visiblePosts(posts []Post) []Post {
+	result := make([]Post, 0, len(posts))
+	for i := range posts {
+		if v.canView(&posts[i]) {
+			result = append(result, posts[i])
+		}
+	}
+	return result
+}
+
+// findVisiblePost loads the post if the viewer can see it. Hidden posts are reported as not found,
+// so their existence isn't revealed.
+func findVisiblePost(ctx context.Context, viewer *Viewer, postID primitive.ObjectID) (*Post, error) {
+	post, err := findPostByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !viewer.canView(post) {
+		return nil, errPostNotFound
+	}
+
+	return post, nil
+}
+
+// narrowsVisibility tells whether changing visibility from one level to the other shows the post to fewer users.
+func narrowsVisibility(from string, to string) bool {
+	return visibilityWidth[to] < visibilityWidth[from]
+}
+
+// parseVisibility validates visibility from a request, empty means public.
+func parseVisibility(visibility string) (string, error) {
+	if visibility == "" {
+		return visibilityPublic, nil
+	}
+
+	if !slices.Contains(visibilities, visibility) {
+		return "", errInvalidVisibility
+	}
+
+	return visibility, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestViewerHidesSuspendedUsers(t *testing.T) {
+	suspendedID := primitive.NewObjectID()
+	viewer := &Viewer{
+		ID:           primitive.NewObjectID(),
+		suspendedIDs: []primitive.ObjectID{suspendedID},
+		suspended:    idSet([]primitive.ObjectID{suspendedID}),
+	}
+
+	post := &Post{Author: suspendedID, Visibility: visibilityPublic}
+	if viewer.canView(post) {
+		t.Error("post of a suspended user is visible")
+	}
+	if viewer.canSee(suspendedID) {
+		t.Error("activity of a suspended user is visible")
+	}
+	if !viewer.canView(&Post{Author: primitive.NewObjectID(), Visibility: visibilityPublic}) {
+		t.Error("public post of another user is hidden")
+	}
+
+	filter := bson.M{}
+	viewer.restrictPosts(filter)
+	conditions := filter["$and"].(bson.A)
+	hidden := conditions[len(conditions)-1].(bson.M)["author"].(bson.M)["$nin"].([]primitive.ObjectID)
+	if len(hidden) != 1 || hidden[0] != suspendedID {
+		t.Errorf("restrictPosts hides %v, want the suspended user", hidden)
+	}
+
+	filter = bson.M{"postId": primitive.NewObjectID()}
+	viewer.restrictUsers(filter, "userId")
+	hidden = filter["$and"].(bson.A)[0].(bson.M)["userId"].(bson.M)["$nin"].([]primitive.ObjectID)
+	if len(hidden) != 1 || hidden[0] != suspendedID {
+		t.Errorf("restrictUsers hides %v, want the suspended user", hidden)
+	}
+}
+
+func TestViewerCanFollow(t *testing.T) {
+	blockedID, suspendedID, otherID := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	viewer := &Viewer{
+		ID:        primitive.NewObjectID(),
+		blocked:   idSet([]primitive.ObjectID{blockedID}),
+		suspended: idSet([]primitive.ObjectID{suspendedID}),
+	}
+
+	for _, test := range []struct {
+		name   string
+		userID primitive.ObjectID
+		want   bool
+	}{
+		{"blocked", blockedID, false},
+		{"suspended", suspendedID, false},
+		{"self", viewer.ID, false},
+		{"other", otherID, true},
+	} {
+		if got := viewer.canFollow(test.userID); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}